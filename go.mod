@@ -1,10 +1,10 @@
 module github.com/timescale/presto-go-client
 
-go 1.17
+go 1.21
 
 require (
 	github.com/ory/dockertest/v3 v3.10.0
-	github.com/stretchr/testify v1.8.2
+	github.com/stretchr/testify v1.8.4
 	gopkg.in/jcmturner/gokrb5.v6 v6.1.1
 )
 
@@ -0,0 +1,99 @@
+// Copyright (c) Facebook, Inc. and its affiliates. All Rights Reserved
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package presto
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// SubmittedQuery identifies a query submitted with Submit. It can be
+// persisted and used later, from any process, to resume fetching the
+// results of the query with Resume.
+type SubmittedQuery struct {
+	QueryId string `json:"queryId"`
+	NextURI string `json:"nextUri"`
+	InfoURI string `json:"infoUri"`
+}
+
+// Submit sends a statement to the server and returns as soon as the server
+// accepted it, without fetching any results.
+//
+// The server abandons queries whose results are not polled for a while
+// (see query.client.timeout in the Presto configuration), so Resume must be
+// called within that delay.
+//
+// The statement goes through the hooks of the connection, such as its
+// tracer and interceptors. When max_concurrent_queries is set, Submit waits
+// for a slot, which is released as soon as the server accepted the
+// statement: fetching the results with Resume takes a slot again.
+func Submit(ctx context.Context, conn *sql.Conn, query string, args ...interface{}) (*SubmittedQuery, error) {
+	var sq *SubmittedQuery
+	err := conn.Raw(func(driverConn interface{}) error {
+		c, ok := driverConn.(*Conn)
+		if !ok {
+			return fmt.Errorf("presto: unexpected driver connection %T", driverConn)
+		}
+		st := &driverStmt{conn: c, query: query}
+		nvs, err := namedValues(st, args)
+		if err != nil {
+			return err
+		}
+		sr, err := st.submit(st.startQuery(ctx), nvs)
+		done := QueryDone{Err: err}
+		if sr != nil {
+			done.QueryId = sr.ID
+			done.QueryStats = sr.Stats
+		}
+		st.run.finish(done)
+		if err != nil {
+			return err
+		}
+		sq = &SubmittedQuery{
+			QueryId: sr.ID,
+			NextURI: sr.NextURI,
+			InfoURI: sr.InfoURI,
+		}
+		return nil
+	})
+	return sq, err
+}
+
+// Resume resumes fetching the results of a query from nextURI, which is the
+// NextURI of a SubmittedQuery. The returned rows behave like the ones of a
+// query run with conn.QueryContext; for statements without results, such as
+// CREATE TABLE AS, iterate over the rows until the end to wait for the query
+// to finish and check rows.Err.
+func Resume(ctx context.Context, conn *sql.Conn, nextURI string) (*sql.Rows, error) {
+	return conn.QueryContext(ctx, "", sql.Named(prestoResumeParam, nextURI))
+}
+
+// checkResumeURI makes sure the connection credentials are only ever sent to
+// the servers the connection points to, and returns the coordinator running
+// the query.
+func (c *Conn) checkResumeURI(nextURI string) (string, error) {
+	u, err := url.Parse(nextURI)
+	if err != nil {
+		return "", fmt.Errorf("presto: malformed resume URI: %w", err)
+	}
+	baseURL := u.Scheme + "://" + u.Host
+	if !c.coordinators.contains(baseURL) {
+		return "", fmt.Errorf("presto: resume URI %q does not point to %s", nextURI, strings.Join(c.coordinators.urls, ", "))
+	}
+	return baseURL, nil
+}
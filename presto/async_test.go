@@ -0,0 +1,133 @@
+// Copyright (c) Facebook, Inc. and its affiliates. All Rights Reserved
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package presto
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingTracer records the queries that are done.
+type recordingTracer struct {
+	mu   sync.Mutex
+	done []QueryDone
+}
+
+func (r *recordingTracer) StartQuery(ctx context.Context, query string) (context.Context, QueryTrace) {
+	return ctx, r
+}
+
+func (r *recordingTracer) StartRequest(ctx context.Context, req *http.Request) RequestTrace {
+	return nil
+}
+
+func (r *recordingTracer) Update(QueryProgressInfo) {}
+
+func (r *recordingTracer) Finish(done QueryDone) {
+	r.mu.Lock()
+	r.done = append(r.done, done)
+	r.mu.Unlock()
+}
+
+func (r *recordingTracer) queries() []QueryDone {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]QueryDone(nil), r.done...)
+}
+
+func TestSubmitResume(t *testing.T) {
+	s := newFakeServer(t)
+	db := s.open(t, "")
+	ctx := context.Background()
+	conn, err := db.Conn(ctx)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	sq, err := Submit(ctx, conn, "SELECT 1")
+	require.NoError(t, err)
+	assert.Equal(t, "q0", sq.QueryId)
+	assert.Empty(t, s.received("GET", "/v1/statement/"), "Submit must not fetch results")
+
+	rows, err := Resume(ctx, conn, sq.NextURI)
+	require.NoError(t, err)
+	var values []int64
+	for rows.Next() {
+		var v int64
+		require.NoError(t, rows.Scan(&v))
+		values = append(values, v)
+	}
+	require.NoError(t, rows.Err())
+	assert.Equal(t, []int64{1}, values)
+	assert.Len(t, s.received("POST", "/v1/statement"), 1)
+
+	_, err = Resume(ctx, conn, "http://example.com/v1/statement/q0/0")
+	assert.ErrorContains(t, err, "does not point to")
+}
+
+func TestResumeURIOfReusedStatement(t *testing.T) {
+	s := newFakeServer(t)
+	db := s.open(t, "")
+	ctx := context.Background()
+	conn, err := db.Conn(ctx)
+	require.NoError(t, err)
+	defer conn.Close()
+	sq, err := Submit(ctx, conn, "SELECT 1")
+	require.NoError(t, err)
+	st, err := conn.PrepareContext(ctx, "SELECT 1")
+	require.NoError(t, err)
+	defer st.Close()
+
+	var v int64
+	require.NoError(t, st.QueryRowContext(ctx, sql.Named(prestoResumeParam, sq.NextURI)).Scan(&v))
+	assert.Len(t, s.received("POST", "/v1/statement"), 1)
+	require.NoError(t, st.QueryRowContext(ctx).Scan(&v))
+	assert.Len(t, s.received("POST", "/v1/statement"), 2, "the resume URI must not carry over to the next execution")
+}
+
+func TestSubmitHooks(t *testing.T) {
+	s := newFakeServer(t)
+	tracer := &recordingTracer{}
+	connector, err := NewConnector(s.dsn("max_concurrent_queries=1"), WithTracer(tracer))
+	require.NoError(t, err)
+	db := sql.OpenDB(connector)
+	defer db.Close()
+	ctx := context.Background()
+	conn, err := db.Conn(ctx)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	// The open rows hold the only slot.
+	rows, err := db.QueryContext(ctx, "SELECT 1")
+	require.NoError(t, err)
+	timeoutCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	_, err = Submit(timeoutCtx, conn, "SELECT 2")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	require.NoError(t, rows.Close())
+
+	sq, err := Submit(ctx, conn, "SELECT 2")
+	require.NoError(t, err)
+	done := tracer.queries()
+	require.Len(t, done, 3)
+	assert.Equal(t, sq.QueryId, done[2].QueryId)
+	assert.NoError(t, done[2].Err)
+}
@@ -0,0 +1,192 @@
+// Copyright (c) Facebook, Inc. and its affiliates. All Rights Reserved
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package presto
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// AuditRecord describes a query run by a connection, once it is done.
+type AuditRecord struct {
+	QueryId        string    `json:"queryId,omitempty"`
+	User           string    `json:"user,omitempty"`
+	Source         string    `json:"source,omitempty"`
+	Catalog        string    `json:"catalog,omitempty"`
+	Schema         string    `json:"schema,omitempty"`
+	Query          string    `json:"query"`
+	ResumeURI      string    `json:"resumeUri,omitempty"` // Next URI a query was resumed from, whose text is then unknown
+	Start          time.Time `json:"start"`
+	End            time.Time `json:"end"`
+	Rows           int64     `json:"rows"`           // Number of rows read by the client
+	ProcessedBytes int64     `json:"processedBytes"` // Number of bytes read by the server
+	State          string    `json:"state"`          // FINISHED, FAILED or CANCELED
+	Error          string    `json:"error,omitempty"`
+}
+
+// AuditSink records the queries run by the connections of a Connector.
+// Its methods are called concurrently.
+type AuditSink interface {
+	// Record is called once for every query, when it succeeds, fails or is
+	// cancelled. Errors are logged, they don't fail the query.
+	Record(AuditRecord) error
+}
+
+// WithAuditSink records the queries run by the connections of the connector
+// to sink. If redactLiterals is set, the string and number literals and the
+// comments of the queries are replaced with question marks in the records.
+func WithAuditSink(sink AuditSink, redactLiterals bool) ConnectorOption {
+	return func(c *Connector) {
+		c.auditSink = sink
+		c.auditRedact = redactLiterals
+	}
+}
+
+// JSONAuditSink writes audit records as JSON lines.
+type JSONAuditSink struct {
+	mu     sync.Mutex
+	enc    *json.Encoder
+	closer io.Closer
+}
+
+// NewJSONAuditSink returns a sink writing audit records to w.
+func NewJSONAuditSink(w io.Writer) *JSONAuditSink {
+	return &JSONAuditSink{enc: json.NewEncoder(w)}
+}
+
+// OpenJSONAuditFile returns a sink appending audit records to the file at
+// path, which is created if needed. The sink must be closed once done.
+func OpenJSONAuditFile(path string) (*JSONAuditSink, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, err
+	}
+	sink := NewJSONAuditSink(f)
+	sink.closer = f
+	return sink, nil
+}
+
+// Record implements the AuditSink interface.
+func (s *JSONAuditSink) Record(rec AuditRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.enc.Encode(rec)
+}
+
+// Close closes the file opened by OpenJSONAuditFile.
+func (s *JSONAuditSink) Close() error {
+	if s.closer == nil {
+		return nil
+	}
+	return s.closer.Close()
+}
+
+// startResumeAudit fills the part of the audit record of a query resumed
+// from nextURI known when it is resumed, with the headers of its requests.
+func (r *queryRun) startResumeAudit(nextURI string, hs http.Header) {
+	r.startAudit("", hs)
+	if r != nil && r.conn.auditSink != nil {
+		r.audit.ResumeURI = nextURI
+	}
+}
+
+// startAudit fills the part of the audit record of a query known when it is
+// submitted, with the headers of its request.
+func (r *queryRun) startAudit(query string, hs http.Header) {
+	if r == nil || r.conn.auditSink == nil {
+		return
+	}
+	headers := r.conn.session.snapshot()
+	for k, v := range hs {
+		headers[k] = v
+	}
+	if r.conn.auditRedact {
+		query = redactLiterals(query)
+	}
+	r.audit = AuditRecord{
+		User:    headers.Get(prestoUserHeader),
+		Source:  headers.Get(prestoSourceHeader),
+		Catalog: headers.Get(prestoCatalogHeader),
+		Schema:  headers.Get(prestoSchemaHeader),
+		Query:   query,
+		Start:   r.start,
+	}
+}
+
+// recordAudit records the audit record of a query once done.
+func (r *queryRun) recordAudit(done QueryDone) {
+	if r.conn.auditSink == nil {
+		return
+	}
+	rec := r.audit
+	if rec.Start.IsZero() {
+		// The query failed before it was submitted.
+		rec.Start = r.start
+	}
+	rec.QueryId = done.QueryId
+	rec.End = time.Now()
+	rec.Rows = done.Rows
+	rec.ProcessedBytes = int64(done.QueryStats.ProcessedBytes)
+	rec.State = "FINISHED"
+	switch {
+	case done.Cancelled || isCancellation(done.Err):
+		rec.State = "CANCELED"
+	case done.Err != nil:
+		rec.State = "FAILED"
+	}
+	if done.Err != nil {
+		rec.Error = done.Err.Error()
+	}
+	if err := r.conn.auditSink.Record(rec); err != nil {
+		r.conn.debug(context.Background(), "presto: audit record failed", "query_id", rec.QueryId, "error", err)
+	}
+}
+
+// redactLiterals replaces the string and number literals of a query, and the
+// text of its comments, with question marks. Queries that can't be split
+// into tokens are replaced entirely.
+func redactLiterals(query string) string {
+	tokens, err := lexSQL(query)
+	if err != nil {
+		return "?"
+	}
+	var b strings.Builder
+	for i := 0; i < len(tokens); i++ {
+		t := tokens[i]
+		switch {
+		case t.kind == sqlString:
+			b.WriteString("?")
+		case t.kind == sqlComment && strings.HasPrefix(t.text, "--"):
+			b.WriteString("-- ?")
+		case t.kind == sqlComment:
+			b.WriteString("/* ? */")
+		case t.kind == sqlWord && t.text[0] >= '0' && t.text[0] <= '9':
+			// Skip the fractional part of decimal numbers.
+			if i+2 < len(tokens) && tokens[i+1].text == "." && tokens[i+2].kind == sqlWord && tokens[i+2].text[0] >= '0' && tokens[i+2].text[0] <= '9' {
+				i += 2
+			}
+			b.WriteString("?")
+		default:
+			b.WriteString(t.text)
+		}
+	}
+	return b.String()
+}
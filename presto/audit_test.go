@@ -0,0 +1,145 @@
+// Copyright (c) Facebook, Inc. and its affiliates. All Rights Reserved
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package presto
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedactLiterals(t *testing.T) {
+	for _, tt := range []struct {
+		query string
+		want  string
+	}{
+		{"SELECT 1", "SELECT ?"},
+		{"SELECT * FROM t WHERE name = 'it''s' AND x > 1.25", "SELECT * FROM t WHERE name = ? AND x > ?"},
+		{"SELECT DATE '2024-01-01', 1e10", "SELECT DATE ?, ?"},
+		{`SELECT "col1", t2.c3 FROM t2`, `SELECT "col1", t2.c3 FROM t2`},
+		{"SELECT ? -- password 'secret'\nFROM t", "SELECT ? -- ?\nFROM t"},
+		{"SELECT /* token=abc */ x", "SELECT /* ? */ x"},
+		{"SELECT 'unterminated", "?"},
+	} {
+		t.Run(tt.query, func(t *testing.T) {
+			assert.Equal(t, tt.want, redactLiterals(tt.query))
+		})
+	}
+}
+
+func TestJSONAuditFile(t *testing.T) {
+	s := newFakeServer(t)
+	s.result = func(query string, _ http.Header) fakeResult {
+		if query == "SELECT fail" {
+			return fakeResult{Columns: []string{"x"}, Error: &stmtError{Message: "failed", ErrorName: "SYNTAX_ERROR"}}
+		}
+		return fakeResult{Columns: []string{"x"}, Pages: [][]queryData{{{1}, {2}}}}
+	}
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	sink, err := OpenJSONAuditFile(path)
+	require.NoError(t, err)
+	connector, err := NewConnector(s.dsn("schema=s&interpolate_params=true"), WithAuditSink(sink, true))
+	require.NoError(t, err)
+	db := sql.OpenDB(connector)
+
+	rows, err := db.Query("SELECT x FROM t WHERE name = 'secret' AND id = ?", 7)
+	require.NoError(t, err)
+	for rows.Next() {
+	}
+	require.NoError(t, rows.Err())
+	_, err = db.Exec("SELECT fail")
+	assert.Error(t, err)
+	require.NoError(t, db.Close())
+	require.NoError(t, sink.Close())
+
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+	var records []AuditRecord
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var rec AuditRecord
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &rec))
+		records = append(records, rec)
+	}
+	require.Len(t, records, 2)
+	assert.Equal(t, "q0", records[0].QueryId)
+	assert.Equal(t, "user", records[0].User)
+	assert.Equal(t, "s", records[0].Schema)
+	assert.Equal(t, "SELECT x FROM t WHERE name = ? AND id = ?", records[0].Query, "literals must be redacted")
+	assert.EqualValues(t, 2, records[0].Rows)
+	assert.Equal(t, "FINISHED", records[0].State)
+	assert.False(t, records[0].End.Before(records[0].Start))
+	assert.Equal(t, "FAILED", records[1].State)
+	assert.Contains(t, records[1].Error, "failed")
+}
+
+// auditRecorder keeps the audit records in memory.
+type auditRecorder struct {
+	mu      sync.Mutex
+	records []AuditRecord
+}
+
+func (r *auditRecorder) Record(rec AuditRecord) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.records = append(r.records, rec)
+	return nil
+}
+
+func TestAuditResume(t *testing.T) {
+	s := newFakeServer(t)
+	sink := &auditRecorder{}
+	connector, err := NewConnector(s.dsn("catalog=c&schema=s"), WithAuditSink(sink, true))
+	require.NoError(t, err)
+	db := sql.OpenDB(connector)
+	defer db.Close()
+	ctx := context.Background()
+	conn, err := db.Conn(ctx)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	sq, err := Submit(ctx, conn, "SELECT 1 -- secret")
+	require.NoError(t, err)
+	rows, err := Resume(ctx, conn, sq.NextURI)
+	require.NoError(t, err)
+	for rows.Next() {
+	}
+	require.NoError(t, rows.Err())
+	require.NoError(t, rows.Close())
+
+	sink.mu.Lock()
+	defer sink.mu.Unlock()
+	require.Len(t, sink.records, 2)
+	assert.Equal(t, "SELECT ? -- ?", sink.records[0].Query)
+	resumed := sink.records[1]
+	assert.Equal(t, "q0", resumed.QueryId)
+	assert.Equal(t, sq.NextURI, resumed.ResumeURI)
+	assert.Empty(t, resumed.Query)
+	assert.Equal(t, "user", resumed.User)
+	assert.Equal(t, "c", resumed.Catalog)
+	assert.Equal(t, "s", resumed.Schema)
+	assert.EqualValues(t, 1, resumed.Rows)
+	assert.Equal(t, "FINISHED", resumed.State)
+}
@@ -0,0 +1,249 @@
+// Copyright (c) Facebook, Inc. and its affiliates. All Rights Reserved
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package presto
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+)
+
+const (
+	// DefaultBulkInsertMaxStatementSize is the default maximum size of the statements run by BulkInsert.
+	DefaultBulkInsertMaxStatementSize = 1 << 20
+
+	// DefaultBulkInsertMaxRows is the default maximum number of rows inserted by each statement run by BulkInsert.
+	DefaultBulkInsertMaxRows = 1000
+)
+
+// execer is implemented by *sql.DB, *sql.Conn and *sql.Tx.
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+// RowSource provides the rows inserted by BulkInsert.
+type RowSource interface {
+	// Next returns the values of the next row, in the order of the columns,
+	// or io.EOF when there are no more rows.
+	Next() ([]interface{}, error)
+}
+
+type sliceRowSource struct {
+	rows [][]interface{}
+}
+
+func (s *sliceRowSource) Next() ([]interface{}, error) {
+	if len(s.rows) == 0 {
+		return nil, io.EOF
+	}
+	row := s.rows[0]
+	s.rows = s.rows[1:]
+	return row, nil
+}
+
+// SliceRowSource returns a RowSource providing rows from a slice.
+func SliceRowSource(rows [][]interface{}) RowSource {
+	return &sliceRowSource{rows: rows}
+}
+
+// BulkInsertOptions configures BulkInsert.
+type BulkInsertOptions struct {
+	MaxStatementSize int // Maximum size of each INSERT statement, in bytes (optional, default is DefaultBulkInsertMaxStatementSize)
+	MaxRows          int // Maximum number of rows inserted by each statement (optional, default is DefaultBulkInsertMaxRows)
+	Concurrency      int // Maximum number of statements running at the same time (optional, default is 1)
+}
+
+// BulkInsertChunk is the result of one of the statements run by BulkInsert.
+type BulkInsertChunk struct {
+	Index       int   // Position of the chunk, starting at 0
+	FirstRow    int   // Position of the first row of the chunk in the source, starting at 0
+	Rows        int   // Number of rows of the chunk
+	UpdateCount int64 // Number of rows inserted, as reported by the server
+	Err         error // Error running the statement, if any
+}
+
+type bulkInsertStatement struct {
+	chunk BulkInsertChunk
+	query string
+}
+
+// BulkInsert inserts the rows of a source into a table, with multi-row
+// INSERT INTO ... VALUES statements. Values are serialized with Serial.
+//
+// table is used as is, so it can be a qualified name; columns are quoted.
+// opts may be nil to use the default options.
+//
+// Statements run concurrently if opts.Concurrency is greater than one, so
+// rows may not be inserted in the order of the source. A failed statement
+// doesn't prevent the others from running: the result of each statement is
+// reported in the returned chunks, and the error reports the first failure.
+// Reading or serializing the rows stops the insertion at the first error.
+func BulkInsert(ctx context.Context, db execer, table string, columns []string, rows RowSource, opts *BulkInsertOptions) ([]BulkInsertChunk, error) {
+	if len(columns) == 0 {
+		return nil, fmt.Errorf("presto: bulk insert into %s without columns", table)
+	}
+	var o BulkInsertOptions
+	if opts != nil {
+		o = *opts
+	}
+	if o.MaxStatementSize <= 0 {
+		o.MaxStatementSize = DefaultBulkInsertMaxStatementSize
+	}
+	if o.MaxRows <= 0 {
+		o.MaxRows = DefaultBulkInsertMaxRows
+	}
+	if o.Concurrency <= 0 {
+		o.Concurrency = 1
+	}
+
+	quoted := make([]string, len(columns))
+	for i, col := range columns {
+		quoted[i] = quoteIdentifier(col)
+	}
+	prefix := "INSERT INTO " + table + " (" + strings.Join(quoted, ", ") + ") VALUES "
+
+	var (
+		mu     sync.Mutex
+		chunks []BulkInsertChunk
+		wg     sync.WaitGroup
+	)
+	statements := make(chan bulkInsertStatement)
+	for i := 0; i < o.Concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for st := range statements {
+				// Statements queued before the context was cancelled are
+				// not sent.
+				err := ctx.Err()
+				if err == nil {
+					var res sql.Result
+					if res, err = db.ExecContext(ctx, st.query); err == nil {
+						st.chunk.UpdateCount, err = res.RowsAffected()
+					}
+				}
+				st.chunk.Err = err
+				mu.Lock()
+				chunks[st.chunk.Index] = st.chunk
+				mu.Unlock()
+			}
+		}()
+	}
+
+	var b strings.Builder
+	var chunk BulkInsertChunk
+	flush := func() bool {
+		if chunk.Rows == 0 {
+			return true
+		}
+		mu.Lock()
+		chunks = append(chunks, chunk)
+		mu.Unlock()
+		if err := ctx.Err(); err != nil {
+			mu.Lock()
+			chunks[chunk.Index].Err = err
+			mu.Unlock()
+			return false
+		}
+		select {
+		case statements <- bulkInsertStatement{chunk: chunk, query: b.String()}:
+		case <-ctx.Done():
+			mu.Lock()
+			chunks[chunk.Index].Err = ctx.Err()
+			mu.Unlock()
+			return false
+		}
+		chunk = BulkInsertChunk{Index: chunk.Index + 1, FirstRow: chunk.FirstRow + chunk.Rows}
+		b.Reset()
+		return true
+	}
+
+	var err error
+	for n := 0; ; n++ {
+		var row []interface{}
+		row, err = rows.Next()
+		if err != nil {
+			if err == io.EOF {
+				err = nil
+				flush()
+			}
+			break
+		}
+		var values string
+		values, err = serialRow(row, len(columns))
+		if err != nil {
+			err = fmt.Errorf("presto: bulk insert row %d: %w", n, err)
+			break
+		}
+		if chunk.Rows > 0 && (b.Len()+len(", ")+len(values) > o.MaxStatementSize || chunk.Rows == o.MaxRows) {
+			if !flush() {
+				break
+			}
+		}
+		if chunk.Rows == 0 {
+			if len(prefix)+len(values) > o.MaxStatementSize {
+				err = fmt.Errorf("presto: bulk insert row %d exceeds the maximum statement size of %d bytes", n, o.MaxStatementSize)
+				break
+			}
+			b.WriteString(prefix)
+		} else {
+			b.WriteString(", ")
+		}
+		b.WriteString(values)
+		chunk.Rows++
+	}
+	close(statements)
+	wg.Wait()
+
+	if err == nil {
+		err = ctx.Err()
+	}
+	if err != nil {
+		return chunks, err
+	}
+	failed := 0
+	var first error
+	for _, c := range chunks {
+		if c.Err != nil {
+			if first == nil {
+				first = c.Err
+			}
+			failed++
+		}
+	}
+	if failed > 0 {
+		return chunks, fmt.Errorf("presto: %d of %d bulk insert statements failed: %w", failed, len(chunks), first)
+	}
+	return chunks, nil
+}
+
+// serialRow returns the values of a row as a parenthesized list.
+func serialRow(row []interface{}, columns int) (string, error) {
+	if len(row) != columns {
+		return "", fmt.Errorf("expected %d values, got %d", columns, len(row))
+	}
+	ss := make([]string, len(row))
+	for i, v := range row {
+		s, err := Serial(v)
+		if err != nil {
+			return "", err
+		}
+		ss[i] = s
+	}
+	return "(" + strings.Join(ss, ", ") + ")", nil
+}
@@ -0,0 +1,199 @@
+// Copyright (c) Facebook, Inc. and its affiliates. All Rights Reserved
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package presto
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// insertResult reports the number of rows inserted by a statement, and
+// fails the statements inserting the value 'bad'.
+func insertResult(query string, header http.Header) fakeResult {
+	if strings.Contains(query, "'bad'") {
+		return fakeResult{Error: &stmtError{Message: "bad value", ErrorName: "INVALID_CAST_ARGUMENT"}}
+	}
+	return fakeResult{UpdateType: "INSERT", UpdateCount: int64(strings.Count(query, "), (") + 1)}
+}
+
+func TestBulkInsert(t *testing.T) {
+	rows := [][]interface{}{{1, "a"}, {2, "b"}, {3, "c"}, {4, "d"}, {5, "e"}}
+	prefix := `INSERT INTO t ("id", "name") VALUES `
+	for _, tt := range []struct {
+		name    string
+		opts    *BulkInsertOptions
+		queries []string
+	}{
+		{"default", nil, []string{prefix + "(1, 'a'), (2, 'b'), (3, 'c'), (4, 'd'), (5, 'e')"}},
+		{"max rows", &BulkInsertOptions{MaxRows: 2}, []string{
+			prefix + "(1, 'a'), (2, 'b')",
+			prefix + "(3, 'c'), (4, 'd')",
+			prefix + "(5, 'e')",
+		}},
+		{"max statement size", &BulkInsertOptions{MaxStatementSize: len(prefix + "(1, 'a'), (2, 'b'), (3")}, []string{
+			prefix + "(1, 'a'), (2, 'b')",
+			prefix + "(3, 'c'), (4, 'd')",
+			prefix + "(5, 'e')",
+		}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newFakeServer(t)
+			s.result = insertResult
+			db := s.open(t, "")
+
+			chunks, err := BulkInsert(context.Background(), db, "t", []string{"id", "name"}, SliceRowSource(rows), tt.opts)
+			require.NoError(t, err)
+			var queries []string
+			for _, r := range s.received("POST", "/v1/statement") {
+				queries = append(queries, r.Body)
+			}
+			assert.Equal(t, tt.queries, queries)
+			require.Len(t, chunks, len(tt.queries))
+			firstRow := 0
+			for i, c := range chunks {
+				n := strings.Count(tt.queries[i], "), (") + 1
+				assert.Equal(t, BulkInsertChunk{Index: i, FirstRow: firstRow, Rows: n, UpdateCount: int64(n)}, c)
+				firstRow += n
+			}
+		})
+	}
+}
+
+func TestBulkInsertErrors(t *testing.T) {
+	s := newFakeServer(t)
+	s.result = insertResult
+	db := s.open(t, "")
+	ctx := context.Background()
+	columns := []string{"id", "name"}
+
+	_, err := BulkInsert(ctx, db, "t", nil, SliceRowSource(nil), nil)
+	assert.Error(t, err)
+
+	_, err = BulkInsert(ctx, db, "t", columns, SliceRowSource([][]interface{}{{1, strings.Repeat("x", 100)}}), &BulkInsertOptions{MaxStatementSize: 100})
+	assert.EqualError(t, err, "presto: bulk insert row 0 exceeds the maximum statement size of 100 bytes")
+
+	_, err = BulkInsert(ctx, db, "t", columns, SliceRowSource([][]interface{}{{1, "a"}, {2}}), nil)
+	assert.EqualError(t, err, "presto: bulk insert row 1: expected 2 values, got 1")
+	assert.Empty(t, s.received("POST", "/v1/statement"))
+
+	// Failed statements don't prevent the others from running.
+	rows := [][]interface{}{{1, "a"}, {2, "bad"}, {3, "c"}, {4, "bad"}, {5, "e"}}
+	chunks, err := BulkInsert(ctx, db, "t", columns, SliceRowSource(rows), &BulkInsertOptions{MaxRows: 1})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "presto: 2 of 5 bulk insert statements failed: ")
+	assert.ErrorContains(t, err, "bad value")
+	require.Len(t, chunks, 5)
+	for i, c := range chunks {
+		assert.Equal(t, i, c.Index)
+		assert.Equal(t, i, c.FirstRow)
+		if i == 1 || i == 3 {
+			assert.ErrorContains(t, c.Err, "bad value")
+			assert.Zero(t, c.UpdateCount)
+		} else {
+			assert.NoError(t, c.Err)
+			assert.Equal(t, int64(1), c.UpdateCount)
+		}
+	}
+}
+
+func TestBulkInsertConcurrency(t *testing.T) {
+	s := newFakeServer(t)
+	s.result = insertResult
+	var mu sync.Mutex
+	running, maxRunning := 0, 0
+	s.handler = func(w http.ResponseWriter, r *http.Request) bool {
+		if r.Method != "POST" {
+			return false
+		}
+		mu.Lock()
+		running++
+		if running > maxRunning {
+			maxRunning = running
+		}
+		mu.Unlock()
+		time.Sleep(50 * time.Millisecond)
+		mu.Lock()
+		running--
+		mu.Unlock()
+		return false
+	}
+	db := s.open(t, "")
+
+	rows := make([][]interface{}, 6)
+	for i := range rows {
+		rows[i] = []interface{}{i, "x"}
+	}
+	chunks, err := BulkInsert(context.Background(), db, "t", []string{"id", "name"}, SliceRowSource(rows), &BulkInsertOptions{MaxRows: 1, Concurrency: 2})
+	require.NoError(t, err)
+	require.Len(t, chunks, 6)
+	for i, c := range chunks {
+		assert.Equal(t, BulkInsertChunk{Index: i, FirstRow: i, Rows: 1, UpdateCount: 1}, c)
+	}
+	assert.Equal(t, 2, maxRunning)
+}
+
+// cancelingSource cancels a context once it provided a number of rows.
+type cancelingSource struct {
+	cancel context.CancelFunc
+	after  int
+	read   int
+}
+
+func (s *cancelingSource) Next() ([]interface{}, error) {
+	if s.read == s.after {
+		s.cancel()
+	}
+	if s.read == 10 {
+		return nil, io.EOF
+	}
+	s.read++
+	return []interface{}{s.read}, nil
+}
+
+func TestBulkInsertCancel(t *testing.T) {
+	s := newFakeServer(t)
+	s.result = insertResult
+	db := s.open(t, "")
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	source := &cancelingSource{cancel: cancel, after: 3}
+	chunks, err := BulkInsert(ctx, db, "t", []string{"id"}, source, &BulkInsertOptions{MaxRows: 1})
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.LessOrEqual(t, source.read, 5)
+	for i, c := range chunks {
+		assert.Equal(t, i, c.Index)
+		if c.Err == nil {
+			assert.Equal(t, int64(1), c.UpdateCount)
+		}
+	}
+	assert.ErrorIs(t, chunks[len(chunks)-1].Err, context.Canceled)
+	// The statement of the row read after the cancellation is not sent,
+	// the ones sent before may still reach the server.
+	time.Sleep(50 * time.Millisecond)
+	posts := s.received("POST", "/v1/statement")
+	assert.LessOrEqual(t, len(posts), 2)
+	for _, r := range posts {
+		assert.NotContains(t, r.Body, "(3)")
+	}
+}
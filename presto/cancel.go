@@ -0,0 +1,261 @@
+// Copyright (c) Facebook, Inc. and its affiliates. All Rights Reserved
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package presto
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+)
+
+// CancelResult is the outcome of CancelQuery and KillQuery.
+type CancelResult struct {
+	QueryId         string
+	State           string // State of the query when it was cancelled, empty if the server doesn't know the query
+	AlreadyFinished bool   // The query was already done, so it was left untouched
+}
+
+// CancelQuery cancels a query by ID, which can have been started by another
+// process. It doesn't fail if the query is already done, or unknown to the
+// server, which forgets about queries some time after they're done: the
+// returned result tells whether the query was still running. When the DSN
+// lists several coordinators, the query is looked up on each of them.
+func CancelQuery(ctx context.Context, db *sql.DB, queryID string) (*CancelResult, error) {
+	res := &CancelResult{QueryId: queryID, AlreadyFinished: true}
+	err := withConn(ctx, db, func(c *Conn) error {
+		baseURL, state, err := c.findQuery(ctx, queryID)
+		if err != nil {
+			return err
+		}
+		res.State = state
+		if res.AlreadyFinished = isDoneState(state); res.AlreadyFinished {
+			return nil
+		}
+		return c.cancelQuery(ctx, baseURL, queryID, "")
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// KillQuery kills a query by ID with the system.runtime.kill_query
+// procedure, which requires the privilege to kill the queries of other
+// users, and reports message as the reason of the failure of the query.
+// Like CancelQuery, it doesn't fail if the query is already done, and it
+// doesn't wait for a slot when max_concurrent_queries is set in the DSN.
+func KillQuery(ctx context.Context, db *sql.DB, queryID, message string) (*CancelResult, error) {
+	// Procedure calls can't be prepared, so the arguments are inlined.
+	id, err := Serial(queryID)
+	if err != nil {
+		return nil, err
+	}
+	msg, err := Serial(message)
+	if err != nil {
+		return nil, err
+	}
+	res := &CancelResult{QueryId: queryID, AlreadyFinished: true}
+	err = withConn(ctx, db, func(c *Conn) error {
+		baseURL, state, err := c.findQuery(ctx, queryID)
+		if err != nil {
+			return err
+		}
+		res.State = state
+		if res.AlreadyFinished = isDoneState(state); res.AlreadyFinished {
+			return nil
+		}
+		// The query to kill may hold one of the slots of the limiter.
+		st := &driverStmt{conn: c, query: "CALL system.runtime.kill_query(query_id => " + id + ", message => " + msg + ")", unlimited: true}
+		defer st.Close()
+		if _, err := st.ExecContext(ctx, nil); err != nil {
+			// The procedure fails if the query finished in the meantime.
+			if state, stateErr := c.queryState(ctx, baseURL, queryID); stateErr == nil && isDoneState(state) {
+				res.State = state
+				res.AlreadyFinished = true
+				return nil
+			}
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// PartialCanceler cancels the leaf stages of a running query, which stops
+// reading new input while the rows produced so far can still be read. It is
+// useful for exploratory queries, once enough rows were read. It is passed to
+// a query as a named argument:
+//
+//	pc := new(presto.PartialCanceler)
+//	rows, err := db.QueryContext(ctx, query, sql.Named("X-Presto-Partial-Cancel", pc))
+//	...
+//	err = pc.PartialCancel(ctx)
+//
+// PartialCancel may be called from any goroutine.
+type PartialCanceler struct {
+	mu   sync.Mutex
+	conn *Conn
+	user string
+	uri  string
+}
+
+// PartialCancel cancels the leaf stages of the query. It returns
+// ErrPartialCancelUnavailable if the query has no running stage, because it
+// didn't start yet or is finished.
+func (pc *PartialCanceler) PartialCancel(ctx context.Context) error {
+	pc.mu.Lock()
+	conn, user, uri := pc.conn, pc.user, pc.uri
+	pc.mu.Unlock()
+	if uri == "" {
+		return ErrPartialCancelUnavailable
+	}
+	return conn.delete(ctx, uri, user)
+}
+
+// bind attaches the canceler to the query submitted by a connection.
+func (pc *PartialCanceler) bind(conn *Conn, user string) {
+	if pc == nil {
+		return
+	}
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	pc.conn = conn
+	pc.user = user
+	pc.uri = ""
+}
+
+// setURI records the partial cancel URI of the latest response of the query.
+func (pc *PartialCanceler) setURI(uri string) {
+	if pc == nil {
+		return
+	}
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	pc.uri = uri
+}
+
+// isDoneState reports whether a query in a given state is done. Unknown
+// queries, with an empty state, are considered done.
+func isDoneState(state string) bool {
+	switch state {
+	case "", "FINISHED", "FAILED", "CANCELED":
+		return true
+	}
+	return false
+}
+
+// withConn runs fn with a connection of db.
+func withConn(ctx context.Context, db *sql.DB, fn func(*Conn) error) error {
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	return conn.Raw(func(driverConn interface{}) error {
+		c, ok := driverConn.(*Conn)
+		if !ok {
+			return fmt.Errorf("presto: unexpected driver connection %T", driverConn)
+		}
+		return fn(c)
+	})
+}
+
+// findQuery returns the coordinator running a query and the state of the
+// query, or an empty state if no coordinator knows the query.
+func (c *Conn) findQuery(ctx context.Context, queryID string) (string, string, error) {
+	var firstErr error
+	for _, baseURL := range c.coordinators.urls {
+		state, err := c.queryState(ctx, baseURL, queryID)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		if state != "" {
+			return baseURL, state, nil
+		}
+	}
+	return "", "", firstErr
+}
+
+// queryState returns the state of a query, or an empty string if the server
+// doesn't know the query.
+func (c *Conn) queryState(ctx context.Context, baseURL, queryID string) (string, error) {
+	var info struct {
+		State string `json:"state"`
+	}
+	if _, err := c.getQuery(ctx, baseURL, queryID, &info); err != nil {
+		return "", err
+	}
+	return info.State, nil
+}
+
+// getQuery decodes the information of a query from a coordinator into v,
+// and reports whether the coordinator knows the query.
+func (c *Conn) getQuery(ctx context.Context, baseURL, queryID string, v interface{}) (bool, error) {
+	req, err := c.newRequest("GET", baseURL+"/v1/query/"+url.PathEscape(queryID), nil, nil)
+	if err != nil {
+		return false, err
+	}
+	resp, err := c.roundTrip(ctx, req)
+	if err != nil {
+		var qferr *ErrQueryFailed
+		if errors.As(err, &qferr) && qferr.StatusCode == http.StatusNotFound {
+			return false, nil
+		}
+		return false, err
+	}
+	defer resp.Body.Close()
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return false, fmt.Errorf("presto: %w", err)
+	}
+	return true, nil
+}
+
+// cancelQuery asks the coordinator running a query to cancel it.
+func (c *Conn) cancelQuery(ctx context.Context, baseURL, queryID, user string) error {
+	return c.delete(ctx, baseURL+"/v1/query/"+url.PathEscape(queryID), user)
+}
+
+// delete sends a DELETE request to a URI returned by the server.
+func (c *Conn) delete(ctx context.Context, uri, user string) error {
+	hs := make(http.Header)
+	if user != "" {
+		hs.Add(prestoUserHeader, user)
+	}
+	req, err := c.newRequest("DELETE", uri, nil, hs)
+	if err != nil {
+		return err
+	}
+	resp, err := c.roundTrip(ctx, req)
+	if err != nil {
+		qferr, ok := err.(*ErrQueryFailed)
+		if ok && qferr.StatusCode == http.StatusNoContent {
+			return nil
+		}
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
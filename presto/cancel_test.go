@@ -0,0 +1,164 @@
+// Copyright (c) Facebook, Inc. and its affiliates. All Rights Reserved
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package presto
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCancelQuery(t *testing.T) {
+	s := newFakeServer(t)
+	states := map[string]string{"running": "RUNNING", "finished": "FINISHED"}
+	s.handler = func(w http.ResponseWriter, r *http.Request) bool {
+		if r.Method != "GET" || !strings.HasPrefix(r.URL.Path, "/v1/query/") {
+			return false
+		}
+		state, ok := states[strings.TrimPrefix(r.URL.Path, "/v1/query/")]
+		if !ok {
+			http.NotFound(w, r)
+			return true
+		}
+		writeJSON(w, map[string]string{"state": state})
+		return true
+	}
+	db := s.open(t, "")
+	ctx := context.Background()
+
+	for _, tt := range []struct {
+		id      string
+		want    CancelResult
+		deleted bool
+	}{
+		{"running", CancelResult{QueryId: "running", State: "RUNNING"}, true},
+		{"finished", CancelResult{QueryId: "finished", State: "FINISHED", AlreadyFinished: true}, false},
+		{"unknown", CancelResult{QueryId: "unknown", AlreadyFinished: true}, false},
+	} {
+		t.Run(tt.id, func(t *testing.T) {
+			res, err := CancelQuery(ctx, db, tt.id)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, *res)
+			assert.Equal(t, tt.deleted, len(s.received("DELETE", "/v1/query/"+tt.id)) == 1)
+		})
+	}
+}
+
+func TestKillQuery(t *testing.T) {
+	s := newFakeServer(t)
+	var mu sync.Mutex
+	states := map[string]string{"q'1": "RUNNING", "done": "FINISHED", "racing": "RUNNING"}
+	s.handler = func(w http.ResponseWriter, r *http.Request) bool {
+		if r.Method != "GET" || !strings.HasPrefix(r.URL.Path, "/v1/query/") {
+			return false
+		}
+		mu.Lock()
+		state, ok := states[strings.TrimPrefix(r.URL.Path, "/v1/query/")]
+		mu.Unlock()
+		if !ok {
+			http.NotFound(w, r)
+			return true
+		}
+		writeJSON(w, map[string]string{"state": state})
+		return true
+	}
+	s.result = func(query string, _ http.Header) fakeResult {
+		if strings.Contains(query, "'racing'") {
+			// The query finishes before it is killed.
+			mu.Lock()
+			states["racing"] = "FINISHED"
+			mu.Unlock()
+			return fakeResult{Error: &stmtError{Message: "Target query is not running: racing", ErrorName: "NOT_SUPPORTED"}}
+		}
+		if strings.HasPrefix(query, "CALL ") {
+			return fakeResult{}
+		}
+		// Holds the slot of the connector until the rows are closed.
+		return fakeResult{Columns: []string{"x"}, Pages: [][]queryData{{{1}}, {{2}}}}
+	}
+	db := s.open(t, "max_concurrent_queries=1")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	rows, err := db.QueryContext(ctx, "SELECT x")
+	require.NoError(t, err)
+	defer rows.Close()
+
+	for _, tt := range []struct {
+		id   string
+		want CancelResult
+		call string
+	}{
+		{"q'1", CancelResult{QueryId: "q'1", State: "RUNNING"}, "CALL system.runtime.kill_query(query_id => 'q''1', message => 'too slow')"},
+		{"done", CancelResult{QueryId: "done", State: "FINISHED", AlreadyFinished: true}, ""},
+		{"unknown", CancelResult{QueryId: "unknown", AlreadyFinished: true}, ""},
+		{"racing", CancelResult{QueryId: "racing", State: "FINISHED", AlreadyFinished: true}, "CALL system.runtime.kill_query(query_id => 'racing', message => 'too slow')"},
+	} {
+		t.Run(tt.id, func(t *testing.T) {
+			before := len(s.received("POST", "/v1/statement"))
+			res, err := KillQuery(ctx, db, tt.id, "too slow")
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, *res)
+			posts := s.received("POST", "/v1/statement")[before:]
+			if tt.call == "" {
+				assert.Empty(t, posts)
+			} else {
+				require.Len(t, posts, 1)
+				assert.Equal(t, tt.call, posts[0].Body)
+			}
+		})
+	}
+}
+
+func TestPartialCancel(t *testing.T) {
+	s := newFakeServer(t)
+	s.result = func(string, http.Header) fakeResult {
+		return fakeResult{Columns: []string{"x"}, Pages: [][]queryData{{{1}}, {{2}}, {{3}}}}
+	}
+	db := s.open(t, "")
+	db.SetMaxOpenConns(1)
+	ctx := context.Background()
+	st, err := db.PrepareContext(ctx, "SELECT x")
+	require.NoError(t, err)
+	defer st.Close()
+
+	pc := new(PartialCanceler)
+	assert.ErrorIs(t, pc.PartialCancel(ctx), ErrPartialCancelUnavailable)
+	rows, err := st.QueryContext(ctx, sql.Named(prestoPartialCancelParam, pc))
+	require.NoError(t, err)
+	require.True(t, rows.Next())
+	require.NoError(t, pc.PartialCancel(ctx))
+	deletes := s.received("DELETE", "/v1/stage/")
+	require.Len(t, deletes, 1)
+	assert.Equal(t, "/v1/stage/q0.0", deletes[0].Path)
+	assert.Equal(t, "user", deletes[0].Header.Get(prestoUserHeader))
+	for rows.Next() {
+	}
+	require.NoError(t, rows.Err())
+	assert.ErrorIs(t, pc.PartialCancel(ctx), ErrPartialCancelUnavailable, "a finished query can't be cancelled")
+
+	// The canceler must not be bound to the next execution of the statement.
+	rows, err = st.QueryContext(ctx)
+	require.NoError(t, err)
+	defer rows.Close()
+	require.True(t, rows.Next())
+	assert.ErrorIs(t, pc.PartialCancel(ctx), ErrPartialCancelUnavailable)
+}
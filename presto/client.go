@@ -0,0 +1,390 @@
+// Copyright (c) Facebook, Inc. and its affiliates. All Rights Reserved
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package presto
+
+import (
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/timescale/presto-go-client/presto/types"
+)
+
+// Client runs queries on a Presto server without going through the
+// database/sql package, and returns rows as native Go values instead of
+// driver.Value.
+//
+// All the queries of a client share the same session: a USE or SET SESSION
+// statement affects the queries that follow it.
+type Client struct {
+	conn *Conn
+}
+
+// NewClient returns a client for the server described by dsn, which has the
+// same format as the one used with sql.Open, and the options of a Connector.
+func NewClient(dsn string, opts ...ConnectorOption) (*Client, error) {
+	connector, err := NewConnector(dsn, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return connector.NewClient(context.Background())
+}
+
+// NewClient returns a client with its own session, which shares the
+// coordinators, the slots to run queries and the hooks of the connector with
+// the other clients and the sql.DB using it.
+func (c *Connector) NewClient(ctx context.Context) (*Client, error) {
+	conn, err := c.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &Client{conn: conn.(*Conn)}, nil
+}
+
+// Close closes the client session.
+func (cl *Client) Close() error {
+	return cl.conn.Close()
+}
+
+// Exec runs a statement that doesn't return rows.
+func (cl *Client) Exec(ctx context.Context, query string, args ...interface{}) (*Result, error) {
+	st := &driverStmt{conn: cl.conn, query: query}
+	defer st.Close()
+	nvs, err := namedValues(st, args)
+	if err != nil {
+		return nil, err
+	}
+	res, err := st.ExecContext(ctx, nvs)
+	if err != nil {
+		return nil, err
+	}
+	return res.(*Result), nil
+}
+
+// Query runs a query and returns an iterator over its rows.
+// The rows must be closed once done with them.
+func (cl *Client) Query(ctx context.Context, query string, args ...interface{}) (*Rows, error) {
+	st := &driverStmt{conn: cl.conn, query: query}
+	nvs, err := namedValues(st, args)
+	if err != nil {
+		return nil, err
+	}
+	dr, err := st.QueryContext(ctx, nvs)
+	if err != nil {
+		st.Close()
+		return nil, err
+	}
+	return &Rows{stmt: st, rows: dr.(*driverRows)}, nil
+}
+
+// Column describes a column of the result of a query.
+type Column struct {
+	Name          string
+	Type          string // Type as text, e.g. array(varchar(10))
+	TypeSignature TypeSignature
+}
+
+// TypeSignature is the parsed type of a column.
+type TypeSignature = types.Signature
+
+// TypeArgument is an argument of a parametric type.
+type TypeArgument = types.Argument
+
+// Rows is an iterator over the rows returned by Client.Query.
+//
+// Values are decoded according to the column types:
+//
+//	boolean                                  bool
+//	tinyint, smallint, integer, bigint       int8, int16, int32, int64
+//	real, double                             float32, float64
+//	varbinary                                []byte
+//	date, timestamp, timestamp with time zone time.Time
+//	array, row                               []interface{}
+//	map                                      map[interface{}]interface{}
+//	other types, such as varchar or decimal  string
+//
+// NULL values are returned as nil.
+type Rows struct {
+	stmt    *driverStmt
+	rows    *driverRows
+	columns []Column
+	values  []interface{}
+	err     error
+}
+
+// Columns returns the columns of the result.
+func (r *Rows) Columns() []Column {
+	if r.columns == nil && r.rows.queryColumns != nil {
+		r.columns = make([]Column, len(r.rows.queryColumns))
+		for i, col := range r.rows.queryColumns {
+			r.columns[i] = Column{
+				Name:          col.Name,
+				Type:          col.Type,
+				TypeSignature: col.TypeSignature,
+			}
+		}
+	}
+	return r.columns
+}
+
+// Next advances to the next row, and returns false when there are no more
+// rows or an error happened, which is then returned by Err.
+func (r *Rows) Next() bool {
+	if r.err != nil {
+		return false
+	}
+	row, err := r.rows.nextRow()
+	if err != nil {
+		r.err = err
+		return false
+	}
+	values := make([]interface{}, len(row))
+	for i, v := range row {
+		values[i], err = decodeValue(r.rows.queryColumns[i].TypeSignature, v)
+		if err != nil {
+			r.err = fmt.Errorf("presto: column %s: %w", r.rows.queryColumns[i].Name, err)
+			return false
+		}
+	}
+	r.values = values
+	return true
+}
+
+// Values returns the values of the current row.
+func (r *Rows) Values() []interface{} {
+	return r.values
+}
+
+// Err returns the error that ended the iteration, if any. Statements
+// without results, such as DDL statements, have no rows and no error.
+func (r *Rows) Err() error {
+	if r.err == io.EOF || r.err == sql.ErrNoRows {
+		return nil
+	}
+	return r.err
+}
+
+// Close stops the query if it's still running and releases its resources.
+func (r *Rows) Close() error {
+	err := r.rows.Close()
+	r.stmt.Close()
+	return err
+}
+
+// decodeValue converts a value decoded from JSON to the Go type matching the
+// type of the column.
+func decodeValue(sig types.Signature, v interface{}) (interface{}, error) {
+	if v == nil {
+		return nil, nil
+	}
+	switch sig.RawType {
+	case "boolean":
+		vv, err := scanNullBool(v)
+		return vv.Bool, err
+	case "tinyint", "smallint", "integer", "bigint":
+		vv, err := scanNullInt64(v)
+		if err != nil {
+			return nil, err
+		}
+		switch sig.RawType {
+		case "tinyint":
+			return int8(vv.Int64), nil
+		case "smallint":
+			return int16(vv.Int64), nil
+		case "integer":
+			return int32(vv.Int64), nil
+		}
+		return vv.Int64, nil
+	case "real":
+		vv, err := scanNullFloat64(v)
+		return float32(vv.Float64), err
+	case "double":
+		vv, err := scanNullFloat64(v)
+		return vv.Float64, err
+	case "varbinary":
+		vv, err := scanNullString(v)
+		if err != nil {
+			return nil, err
+		}
+		return base64.StdEncoding.DecodeString(vv.String)
+	case "date":
+		return decodeTime(v, "2006-01-02")
+	case "timestamp":
+		return decodeTime(v, "2006-01-02 15:04:05.999999999")
+	case "timestamp with time zone":
+		return decodeTimestampTz(v)
+	case "array":
+		return decodeArray(sig, v)
+	case "map":
+		return decodeMap(sig, v)
+	case "row":
+		return decodeRow(sig, v)
+	case "json", "char", "varchar", "decimal",
+		"time", "time with time zone",
+		"interval year to month", "interval day to second",
+		"ipprefix", "ipaddress", "uuid":
+		vv, err := scanNullString(v)
+		return vv.String, err
+	default:
+		return v, nil
+	}
+}
+
+// unmarshalNested decodes arrays, maps and rows, which Presto sends as
+// strings containing serialized JSON.
+func unmarshalNested(v interface{}, dst interface{}) (bool, error) {
+	str, ok := v.(string)
+	if !ok {
+		return false, nil
+	}
+	d := json.NewDecoder(strings.NewReader(str))
+	d.UseNumber()
+	if err := d.Decode(dst); err != nil {
+		return true, fmt.Errorf("cannot convert %v (%T): %w", v, v, err)
+	}
+	return true, nil
+}
+
+func decodeArray(sig types.Signature, v interface{}) (interface{}, error) {
+	a, ok := v.([]interface{})
+	if !ok {
+		if ok, err := unmarshalNested(v, &a); !ok || err != nil {
+			if err == nil {
+				err = fmt.Errorf("cannot convert %v (%T) to slice", v, v)
+			}
+			return nil, err
+		}
+	}
+	result := make([]interface{}, len(a))
+	for i, e := range a {
+		var err error
+		if result[i], err = decodeValue(sig.Elem(0), e); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+func decodeMap(sig types.Signature, v interface{}) (interface{}, error) {
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		if ok, err := unmarshalNested(v, &m); !ok || err != nil {
+			if err == nil {
+				err = fmt.Errorf("cannot convert %v (%T) to map", v, v)
+			}
+			return nil, err
+		}
+	}
+	keyType, valueType := sig.Elem(0), sig.Elem(1)
+	result := make(map[interface{}]interface{}, len(m))
+	for k, e := range m {
+		key, err := decodeValue(keyType, mapKeyValue(keyType, k))
+		if err != nil {
+			return nil, err
+		}
+		if result[key], err = decodeValue(valueType, e); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// mapKeyValue returns a map key, which is always a string in JSON, as the
+// value JSON would have used for the key type.
+func mapKeyValue(keyType types.Signature, k string) interface{} {
+	switch keyType.RawType {
+	case "tinyint", "smallint", "integer", "bigint", "real", "double":
+		return json.Number(k)
+	case "boolean":
+		b, err := strconv.ParseBool(k)
+		if err != nil {
+			return k
+		}
+		return b
+	}
+	return k
+}
+
+func decodeRow(sig types.Signature, v interface{}) (interface{}, error) {
+	if m, ok := v.(map[string]interface{}); ok {
+		// Rows may be encoded as objects keyed by field name.
+		fields := make([]interface{}, len(sig.Arguments))
+		for i, arg := range sig.Arguments {
+			fields[i] = m[arg.FieldName]
+		}
+		v = fields
+	}
+	a, ok := v.([]interface{})
+	if !ok {
+		if ok, err := unmarshalNested(v, &a); !ok || err != nil {
+			if err == nil {
+				err = fmt.Errorf("cannot convert %v (%T) to row", v, v)
+			}
+			return nil, err
+		}
+	}
+	result := make([]interface{}, len(a))
+	for i, e := range a {
+		var err error
+		if result[i], err = decodeValue(sig.Elem(i), e); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+func decodeTime(v interface{}, layout string) (interface{}, error) {
+	vv, err := scanNullString(v)
+	if err != nil {
+		return nil, err
+	}
+	return time.Parse(layout, vv.String)
+}
+
+func decodeTimestampTz(v interface{}) (interface{}, error) {
+	vv, err := scanNullString(v)
+	if err != nil {
+		return nil, err
+	}
+	i := strings.LastIndex(vv.String, " ")
+	if i < 0 {
+		return nil, fmt.Errorf("cannot convert %v (%T) to time.Time", v, v)
+	}
+	loc, err := parseZone(vv.String[i+1:])
+	if err != nil {
+		return nil, err
+	}
+	return time.ParseInLocation("2006-01-02 15:04:05.999999999", vv.String[:i], loc)
+}
+
+// parseZone returns the location for a zone ID, e.g. UTC or Europe/Paris, or
+// an offset, e.g. +02:00.
+func parseZone(zone string) (*time.Location, error) {
+	if strings.HasPrefix(zone, "+") || strings.HasPrefix(zone, "-") {
+		t, err := time.Parse("-07:00", zone)
+		if err != nil {
+			return nil, err
+		}
+		_, offset := t.Zone()
+		return time.FixedZone(zone, offset), nil
+	}
+	return time.LoadLocation(zone)
+}
@@ -0,0 +1,116 @@
+// Copyright (c) Facebook, Inc. and its affiliates. All Rights Reserved
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package presto
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/timescale/presto-go-client/presto/types"
+)
+
+func TestClientQuery(t *testing.T) {
+	s := newFakeServer(t)
+	s.result = func(query string, _ http.Header) fakeResult {
+		if query == "CREATE TABLE t (x bigint)" {
+			return fakeResult{}
+		}
+		return fakeResult{Columns: []string{"x"}, Pages: [][]queryData{{{json.Number("1")}}, {{json.Number("2")}}}}
+	}
+	client, err := NewClient(s.dsn(""))
+	require.NoError(t, err)
+	defer client.Close()
+	ctx := context.Background()
+
+	rows, err := client.Query(ctx, "SELECT x FROM t")
+	require.NoError(t, err)
+	var values []interface{}
+	for rows.Next() {
+		values = append(values, rows.Values()...)
+	}
+	require.NoError(t, rows.Err())
+	require.NoError(t, rows.Close())
+	assert.Equal(t, []interface{}{int64(1), int64(2)}, values)
+	assert.Equal(t, []Column{{Name: "x", Type: "bigint", TypeSignature: TypeSignature{RawType: "bigint"}}}, rows.Columns())
+
+	rows, err = client.Query(ctx, "CREATE TABLE t (x bigint)")
+	require.NoError(t, err)
+	assert.False(t, rows.Next())
+	assert.NoError(t, rows.Err(), "statements without results must not report sql.ErrNoRows")
+	require.NoError(t, rows.Close())
+}
+
+func TestConnectorClientSharesSlots(t *testing.T) {
+	s := newFakeServer(t)
+	connector, err := NewConnector(s.dsn("max_concurrent_queries=1"))
+	require.NoError(t, err)
+	db := sql.OpenDB(connector)
+	defer db.Close()
+	client, err := connector.NewClient(context.Background())
+	require.NoError(t, err)
+	defer client.Close()
+
+	rows, err := db.Query("SELECT 1")
+	require.NoError(t, err)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err = client.Query(ctx, "SELECT 2")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	require.NoError(t, rows.Close())
+
+	crows, err := client.Query(context.Background(), "SELECT 2")
+	require.NoError(t, err)
+	require.NoError(t, crows.Close())
+}
+
+func TestDecodeValue(t *testing.T) {
+	for _, tt := range []struct {
+		typ   string
+		value interface{}
+		want  interface{}
+	}{
+		{"boolean", true, true},
+		{"integer", json.Number("42"), int32(42)},
+		{"bigint", json.Number("42"), int64(42)},
+		{"double", json.Number("1.5"), 1.5},
+		{"varchar(10)", "abc", "abc"},
+		{"varbinary", "AQI=", []byte{1, 2}},
+		{"date", "2024-02-29", time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{"timestamp(3)", "2024-02-29 10:11:12.123", time.Date(2024, 2, 29, 10, 11, 12, 123000000, time.UTC)},
+		{"timestamp(0) with time zone", "2024-02-29 10:11:12 +02:00", time.Date(2024, 2, 29, 8, 11, 12, 0, time.UTC)},
+		{"array(bigint)", []interface{}{json.Number("1"), nil}, []interface{}{int64(1), nil}},
+		{"map(varchar, bigint)", map[string]interface{}{"a": json.Number("1")}, map[interface{}]interface{}{"a": int64(1)}},
+		{"row(a bigint, b varchar)", []interface{}{json.Number("1"), "x"}, []interface{}{int64(1), "x"}},
+		{"bigint", nil, nil},
+	} {
+		t.Run(tt.typ, func(t *testing.T) {
+			sig, err := types.Parse(tt.typ)
+			require.NoError(t, err)
+			got, err := decodeValue(sig, tt.value)
+			require.NoError(t, err)
+			if want, ok := tt.want.(time.Time); ok {
+				assert.True(t, want.Equal(got.(time.Time)), "got %v", got)
+				return
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
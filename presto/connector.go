@@ -0,0 +1,110 @@
+// Copyright (c) Facebook, Inc. and its affiliates. All Rights Reserved
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package presto
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"log/slog"
+)
+
+// Connector opens connections to the server described by a DSN, with
+// options that can't be expressed in the DSN, such as tracing hooks. It is
+// used with sql.OpenDB:
+//
+//	connector, err := presto.NewConnector(dsn, presto.WithTracer(tracer))
+//	...
+//	db := sql.OpenDB(connector)
+type Connector struct {
+	dsn          string
+	coordinators *coordinatorPool
+	limiter      *queryLimiter
+	tracer       Tracer
+	metrics      Metrics
+	logger       *slog.Logger
+	interceptors []Interceptor
+	auditSink    AuditSink
+	auditRedact  bool
+}
+
+// ConnectorOption configures a Connector.
+type ConnectorOption func(*Connector)
+
+// WithTracer traces the queries run by the connections of the connector.
+func WithTracer(tracer Tracer) ConnectorOption {
+	return func(c *Connector) {
+		c.tracer = tracer
+	}
+}
+
+var (
+	_ driver.Connector     = &Connector{}
+	_ driver.DriverContext = &Driver{}
+)
+
+// NewConnector returns a connector for dsn, which has the same format as the
+// one used with sql.Open.
+func NewConnector(dsn string, opts ...ConnectorOption) (*Connector, error) {
+	serverURL, hosts, err := parseDSN(dsn)
+	if err != nil {
+		return nil, fmt.Errorf("presto: malformed dsn: %w", err)
+	}
+	coordinators, err := newCoordinatorPool(serverURL, hosts)
+	if err != nil {
+		return nil, err
+	}
+	limiter, err := newQueryLimiter(serverURL.Query().Get("max_concurrent_queries"))
+	if err != nil {
+		return nil, err
+	}
+	c := &Connector{dsn: dsn, coordinators: coordinators, limiter: limiter}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c, nil
+}
+
+// Connect implements the driver.Connector interface.
+func (c *Connector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := newConn(c.dsn)
+	if err != nil {
+		if c.logger != nil {
+			c.logger.DebugContext(ctx, "presto: connection failed", "error", err)
+		}
+		return nil, err
+	}
+	// The connections share the health of the coordinators and the slots
+	// to run queries.
+	conn.coordinators = c.coordinators
+	conn.limiter = c.limiter
+	conn.tracer = c.tracer
+	conn.metrics = c.metrics
+	conn.logger = c.logger
+	conn.interceptors = c.interceptors
+	conn.auditSink = c.auditSink
+	conn.auditRedact = c.auditRedact
+	return conn, nil
+}
+
+// Driver implements the driver.Connector interface.
+func (c *Connector) Driver() driver.Driver {
+	return &Driver{}
+}
+
+// OpenConnector implements the driver.DriverContext interface.
+func (d *Driver) OpenConnector(name string) (driver.Connector, error) {
+	return NewConnector(name)
+}
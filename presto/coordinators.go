@@ -0,0 +1,242 @@
+// Copyright (c) Facebook, Inc. and its affiliates. All Rights Reserved
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package presto
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+// Load balancing strategies, set with load_balancing in the DSN, to pick the
+// coordinator running each query when the DSN lists several hosts, e.g.
+// http://user@c1:8080,c2:8080?load_balancing=round_robin.
+const (
+	LoadBalancingFailover   = "failover"    // Use the first available host, in order (default)
+	LoadBalancingRoundRobin = "round_robin" // Use the available hosts in turn
+	LoadBalancingRandom     = "random"      // Use a random available host
+)
+
+// DefaultHealthCheckInterval is the time a coordinator that could not be
+// reached is left out, before checking its health again.
+var DefaultHealthCheckInterval = 30 * time.Second
+
+// healthCheckTimeout is the timeout of the requests checking the health of
+// a coordinator.
+const healthCheckTimeout = 5 * time.Second
+
+// parseDSN parses a DSN which may list several hosts separated by commas,
+// which url.Parse rejects. The returned URL has the first host.
+func parseDSN(dsn string) (*url.URL, []string, error) {
+	var hosts []string
+	if i := strings.Index(dsn, "://"); i >= 0 {
+		start := i + len("://")
+		end := len(dsn)
+		if j := strings.IndexAny(dsn[start:], "/?#"); j >= 0 {
+			end = start + j
+		}
+		if at := strings.LastIndexByte(dsn[start:end], '@'); at >= 0 {
+			start += at + 1
+		}
+		for _, host := range strings.Split(dsn[start:end], ",") {
+			if host = strings.TrimSpace(host); host != "" {
+				hosts = append(hosts, host)
+			}
+		}
+		if len(hosts) > 1 {
+			dsn = dsn[:start] + hosts[0] + dsn[end:]
+		}
+	}
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(hosts) == 0 {
+		hosts = []string{u.Host}
+	}
+	return u, hosts, nil
+}
+
+// coordinatorPool holds the coordinators listed in a DSN. It is shared by
+// the connections of a Connector.
+type coordinatorPool struct {
+	urls                []string // scheme://host of each coordinator
+	strategy            string
+	healthCheckInterval time.Duration
+	next                uint32
+
+	mu        sync.Mutex
+	downUntil map[string]time.Time
+	checking  map[string]bool // coordinators being checked
+}
+
+func newCoordinatorPool(serverURL *url.URL, hosts []string) (*coordinatorPool, error) {
+	query := serverURL.Query()
+	p := &coordinatorPool{
+		strategy:            LoadBalancingFailover,
+		healthCheckInterval: DefaultHealthCheckInterval,
+		downUntil:           make(map[string]time.Time),
+		checking:            make(map[string]bool),
+	}
+	for _, host := range hosts {
+		p.urls = append(p.urls, serverURL.Scheme+"://"+host)
+	}
+	if v := query.Get("load_balancing"); v != "" {
+		switch v {
+		case LoadBalancingFailover, LoadBalancingRoundRobin, LoadBalancingRandom:
+			p.strategy = v
+		default:
+			return nil, fmt.Errorf("presto: unknown load_balancing strategy %q", v)
+		}
+	}
+	if v := query.Get("health_check_interval"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return nil, fmt.Errorf("presto: malformed health_check_interval: %w", err)
+		}
+		p.healthCheckInterval = d
+	}
+	return p, nil
+}
+
+// contains reports whether baseURL is one of the coordinators.
+func (p *coordinatorPool) contains(baseURL string) bool {
+	for _, u := range p.urls {
+		if u == baseURL {
+			return true
+		}
+	}
+	return false
+}
+
+// candidates returns the coordinators to try, in order, to run a query.
+// Coordinators that could not be reached recently come last; the health of
+// the ones left out for longer than the health check interval is checked
+// again in the background with the connection c.
+func (p *coordinatorPool) candidates(c *Conn) []string {
+	if len(p.urls) == 1 {
+		return p.urls
+	}
+	ordered := make([]string, len(p.urls))
+	switch p.strategy {
+	case LoadBalancingRoundRobin:
+		n := int(atomic.AddUint32(&p.next, 1) - 1)
+		for i := range ordered {
+			ordered[i] = p.urls[(n+i)%len(p.urls)]
+		}
+	case LoadBalancingRandom:
+		for i, j := range rand.Perm(len(p.urls)) {
+			ordered[i] = p.urls[j]
+		}
+	default:
+		copy(ordered, p.urls)
+	}
+	var up, down []string
+	for _, u := range ordered {
+		if p.isDown(c, u) {
+			down = append(down, u)
+		} else {
+			up = append(up, u)
+		}
+	}
+	return append(up, down...)
+}
+
+// isDown reports whether a coordinator is considered unavailable. A
+// coordinator stays unavailable until a health check succeeds; only one
+// check per coordinator runs at a time, and queries don't wait for it.
+func (p *coordinatorPool) isDown(c *Conn, baseURL string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	until, ok := p.downUntil[baseURL]
+	if !ok {
+		return false
+	}
+	if time.Now().After(until) && !p.checking[baseURL] {
+		p.checking[baseURL] = true
+		go p.checkHealth(c, baseURL)
+	}
+	return true
+}
+
+// checkHealth checks the health of a coordinator that could not be reached,
+// and leaves it out for another health check interval if it is still
+// unavailable.
+func (p *coordinatorPool) checkHealth(c *Conn, baseURL string) {
+	ctx, cancel := context.WithTimeout(context.Background(), healthCheckTimeout)
+	defer cancel()
+	err := c.checkHealth(ctx, baseURL)
+	if err != nil {
+		c.debug(ctx, "presto: coordinator still unavailable", "url", baseURL, "error", err)
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.checking, baseURL)
+	if err != nil {
+		p.downUntil[baseURL] = time.Now().Add(p.healthCheckInterval)
+	} else {
+		delete(p.downUntil, baseURL)
+	}
+}
+
+// markDown leaves a coordinator out for the health check interval.
+func (p *coordinatorPool) markDown(baseURL string) {
+	p.mu.Lock()
+	p.downUntil[baseURL] = time.Now().Add(p.healthCheckInterval)
+	p.mu.Unlock()
+}
+
+// checkHealth checks that a coordinator is up and ready to run queries.
+func (c *Conn) checkHealth(ctx context.Context, baseURL string) error {
+	info, err := c.serverInfo(ctx, baseURL)
+	if err != nil {
+		return err
+	}
+	if info.Starting {
+		return ErrServerStarting
+	}
+	return nil
+}
+
+// isUnreachable reports whether a request failed because the connection to
+// the server could not be made, so that it can be sent to another
+// coordinator. Once connected, the server may have accepted the statement
+// even if the request failed afterwards, e.g. with a timeout, and sending it
+// again could run it twice.
+func isUnreachable(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) || errors.Is(err, syscall.ECONNREFUSED) {
+		return true
+	}
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
+
+// coordinator returns the coordinator to send requests unrelated to a
+// running query to.
+func (c *Conn) coordinator() string {
+	return c.coordinators.candidates(c)[0]
+}
@@ -0,0 +1,184 @@
+// Copyright (c) Facebook, Inc. and its affiliates. All Rights Reserved
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package presto
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseDSN(t *testing.T) {
+	for _, tt := range []struct {
+		dsn   string
+		url   string
+		hosts []string
+	}{
+		{"http://localhost:8080", "http://localhost:8080", []string{"localhost:8080"}},
+		{"http://user@localhost:8080?catalog=c", "http://user@localhost:8080?catalog=c", []string{"localhost:8080"}},
+		{"http://user@c1:8080,c2:8080", "http://user@c1:8080", []string{"c1:8080", "c2:8080"}},
+		{"https://user:p%40ss@c1,c2:8443,/path?schema=s", "https://user:p%40ss@c1/path?schema=s", []string{"c1", "c2:8443"}},
+		{"http://a@b@c1:8080, c2:8080#f", "http://a%40b@c1:8080#f", []string{"c1:8080", "c2:8080"}},
+	} {
+		t.Run(tt.dsn, func(t *testing.T) {
+			u, hosts, err := parseDSN(tt.dsn)
+			require.NoError(t, err)
+			assert.Equal(t, tt.url, u.String())
+			assert.Equal(t, tt.hosts, hosts)
+		})
+	}
+}
+
+func TestNewCoordinatorPool(t *testing.T) {
+	u, hosts, err := parseDSN("http://user@c1:8080,c2:8080?load_balancing=round_robin&health_check_interval=1m")
+	require.NoError(t, err)
+	p, err := newCoordinatorPool(u, hosts)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"http://c1:8080", "http://c2:8080"}, p.urls)
+	assert.Equal(t, LoadBalancingRoundRobin, p.strategy)
+	assert.Equal(t, time.Minute, p.healthCheckInterval)
+	assert.Equal(t, []string{"http://c1:8080", "http://c2:8080"}, p.candidates(nil))
+	assert.Equal(t, []string{"http://c2:8080", "http://c1:8080"}, p.candidates(nil))
+
+	for _, query := range []string{"load_balancing=first", "health_check_interval=1"} {
+		u, err := url.Parse("http://c1:8080?" + query)
+		require.NoError(t, err)
+		_, err = newCoordinatorPool(u, []string{u.Host})
+		assert.Error(t, err, query)
+	}
+}
+
+func TestIsUnreachable(t *testing.T) {
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	dial := &url.Error{Op: "Post", URL: "http://c1", Err: &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("i/o timeout")}}
+	for _, tt := range []struct {
+		name string
+		ctx  context.Context
+		err  error
+		want bool
+	}{
+		{"dial", context.Background(), &ErrQueryFailed{Reason: dial}, true},
+		{"refused", context.Background(), &ErrQueryFailed{Reason: &net.OpError{Op: "read", Err: os.NewSyscallError("connect", syscall.ECONNREFUSED)}}, true},
+		{"dns", context.Background(), &ErrQueryFailed{Reason: &net.DNSError{Err: "no such host", Name: "c1"}}, true},
+		{"canceled", canceled, &ErrQueryFailed{Reason: dial}, false},
+		{"read", context.Background(), &ErrQueryFailed{Reason: &net.OpError{Op: "read", Err: errors.New("connection reset by peer")}}, false},
+		{"timeout", context.Background(), &ErrQueryFailed{Reason: &url.Error{Op: "Post", URL: "http://c1", Err: context.DeadlineExceeded}}, false},
+		{"status", context.Background(), &ErrQueryFailed{StatusCode: http.StatusBadGateway}, false},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, isUnreachable(tt.ctx, tt.err))
+		})
+	}
+}
+
+// closedAddr returns the address of a listener that was closed, to which
+// connections are refused.
+func closedAddr(t *testing.T) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := l.Addr().String()
+	l.Close()
+	return addr
+}
+
+func TestFailover(t *testing.T) {
+	s := newFakeServer(t)
+	down := closedAddr(t)
+	connector, err := NewConnector("http://user@" + down + "," + s.Listener.Addr().String())
+	require.NoError(t, err)
+	db := sql.OpenDB(connector)
+	defer db.Close()
+
+	for i := 0; i < 2; i++ {
+		var n int
+		require.NoError(t, db.QueryRow("SELECT 1").Scan(&n))
+		assert.Equal(t, 1, n)
+	}
+	assert.Len(t, s.received("POST", "/v1/statement"), 2)
+	assert.Equal(t, []string{s.URL, "http://" + down}, connector.coordinators.candidates(nil))
+}
+
+func TestFailoverAfterConnecting(t *testing.T) {
+	first := newFakeServer(t)
+	first.handler = func(w http.ResponseWriter, r *http.Request) bool {
+		if r.Method != "POST" {
+			return false
+		}
+		// The statement was accepted, but the response is too late.
+		<-r.Context().Done()
+		return true
+	}
+	second := newFakeServer(t)
+	db, err := sql.Open("presto", "http://user@"+first.Listener.Addr().String()+","+second.Listener.Addr().String()+"?submit_timeout=100ms")
+	require.NoError(t, err)
+	defer db.Close()
+
+	_, err = db.Query("SELECT 1")
+	assert.Error(t, err)
+	assert.Len(t, first.received("POST", "/v1/statement"), 1)
+	assert.Empty(t, second.received("POST", "/v1/statement"))
+}
+
+func TestHealthCheck(t *testing.T) {
+	release := make(chan struct{})
+	first := newFakeServer(t)
+	first.handler = func(w http.ResponseWriter, r *http.Request) bool {
+		if r.URL.Path == "/v1/info" {
+			<-release
+		}
+		return false
+	}
+	second := newFakeServer(t)
+	connector, err := NewConnector("http://user@" + first.Listener.Addr().String() + "," + second.Listener.Addr().String())
+	require.NoError(t, err)
+	conn, err := connector.Connect(context.Background())
+	require.NoError(t, err)
+	defer conn.Close()
+	c := conn.(*Conn)
+	p := connector.coordinators
+	p.downUntil[first.URL] = time.Now().Add(-time.Second)
+
+	// Queries don't wait for the health check, which runs once.
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.Equal(t, []string{second.URL, first.URL}, p.candidates(c))
+		}()
+	}
+	wg.Wait()
+	require.Eventually(t, func() bool {
+		return len(first.received("GET", "/v1/info")) == 1
+	}, time.Second, 10*time.Millisecond)
+
+	close(release)
+	require.Eventually(t, func() bool {
+		return p.candidates(c)[0] == first.URL
+	}, time.Second, 10*time.Millisecond)
+	assert.Len(t, first.received("GET", "/v1/info"), 1)
+}
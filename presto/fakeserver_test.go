@@ -0,0 +1,201 @@
+// Copyright (c) Facebook, Inc. and its affiliates. All Rights Reserved
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package presto
+
+import (
+	"database/sql"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// fakeServer is a coordinator serving canned results, which records the
+// requests it receives.
+type fakeServer struct {
+	*httptest.Server
+
+	// handler, if set, is called before the default handling of a request,
+	// which is skipped when it returns true.
+	handler func(w http.ResponseWriter, r *http.Request) bool
+	// result, if set, returns the result of a statement. By default it is a
+	// single bigint column with a single row.
+	result func(query string, header http.Header) fakeResult
+
+	mu       sync.Mutex
+	requests []fakeRequest
+	queries  map[string]fakeResult
+}
+
+type fakeRequest struct {
+	Method string
+	Path   string
+	Header http.Header
+	Body   string
+}
+
+// fakeResult is the result of a statement. Each page is sent by a separate
+// response, after the response to the statement itself.
+type fakeResult struct {
+	Columns  []string
+	Types    []string // Types of the columns, bigint by default
+	Pages    [][]queryData
+	Header   http.Header // Headers of the response to the statement
+	Warnings []stmtWarning
+	Error    *stmtError // Error sent with the last page
+
+	// Sent with the last page
+	UpdateType  string
+	UpdateCount int64
+	Stats       stmtStats // Final statistics, with the state set by the server
+}
+
+func newFakeServer(t *testing.T) *fakeServer {
+	s := &fakeServer{queries: make(map[string]fakeResult)}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// fakeRows is the default result of a statement.
+var fakeRows = fakeResult{Columns: []string{"_col0"}, Pages: [][]queryData{{{1}}}}
+
+func (s *fakeServer) serve(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	r.Body = io.NopCloser(strings.NewReader(string(body)))
+	s.mu.Lock()
+	s.requests = append(s.requests, fakeRequest{Method: r.Method, Path: r.URL.Path, Header: r.Header.Clone(), Body: string(body)})
+	s.mu.Unlock()
+	if s.handler != nil && s.handler(w, r) {
+		return
+	}
+	switch {
+	case r.Method == "GET" && r.URL.Path == "/v1/info":
+		writeJSON(w, map[string]interface{}{
+			"nodeVersion": map[string]string{"version": "0.287"},
+			"environment": "test",
+			"coordinator": true,
+			"uptime":      "1.00m",
+		})
+	case r.Method == "POST" && r.URL.Path == "/v1/statement":
+		result := fakeRows
+		if s.result != nil {
+			result = s.result(string(body), r.Header)
+		}
+		s.mu.Lock()
+		id := "q" + strconv.Itoa(len(s.queries))
+		s.queries[id] = result
+		s.mu.Unlock()
+		for k, v := range result.Header {
+			w.Header()[k] = v
+		}
+		writeJSON(w, map[string]interface{}{
+			"id":       id,
+			"nextUri":  s.URL + "/v1/statement/" + id + "/0",
+			"stats":    stmtStats{State: "QUEUED"},
+			"warnings": result.Warnings,
+		})
+	case r.Method == "GET" && strings.HasPrefix(r.URL.Path, "/v1/statement/"):
+		parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/v1/statement/"), "/")
+		s.mu.Lock()
+		result, ok := s.queries[parts[0]]
+		s.mu.Unlock()
+		page, err := strconv.Atoi(parts[len(parts)-1])
+		if !ok || err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		resp := map[string]interface{}{"id": parts[0], "stats": stmtStats{State: "RUNNING"}}
+		columns := make([]map[string]interface{}, len(result.Columns))
+		for i, name := range result.Columns {
+			typ := "bigint"
+			if i < len(result.Types) {
+				typ = result.Types[i]
+			}
+			// Type signatures may be sent as text, which the driver parses.
+			columns[i] = map[string]interface{}{"name": name, "type": typ, "typeSignature": typ}
+		}
+		if len(columns) > 0 {
+			resp["columns"] = columns
+		}
+		if page < len(result.Pages) {
+			resp["data"] = result.Pages[page]
+		}
+		if page+1 < len(result.Pages) {
+			resp["nextUri"] = s.URL + "/v1/statement/" + parts[0] + "/" + strconv.Itoa(page+1)
+			resp["partialCancelUri"] = s.URL + "/v1/stage/" + parts[0] + ".0"
+		} else {
+			stats := result.Stats
+			stats.State = "FINISHED"
+			if result.Error != nil {
+				resp["error"] = result.Error
+				stats.State = "FAILED"
+			}
+			resp["stats"] = stats
+			if result.UpdateType != "" {
+				resp["updateType"] = result.UpdateType
+				resp["updateCount"] = result.UpdateCount
+			}
+		}
+		writeJSON(w, resp)
+	case r.Method == "DELETE":
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// dsn returns the DSN of the server, with the query string query.
+func (s *fakeServer) dsn(query string) string {
+	dsn := "http://user@" + s.Listener.Addr().String()
+	if query != "" {
+		dsn += "?" + query
+	}
+	return dsn
+}
+
+// open opens a database connected to the server with the DSN query string
+// query, closed at the end of the test.
+func (s *fakeServer) open(t *testing.T, query string) *sql.DB {
+	db, err := sql.Open("presto", s.dsn(query))
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+// received returns the requests received so far with the method, and a
+// path starting with prefix.
+func (s *fakeServer) received(method, prefix string) []fakeRequest {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var requests []fakeRequest
+	for _, r := range s.requests {
+		if r.Method == method && strings.HasPrefix(r.Path, prefix) {
+			requests = append(requests, r)
+		}
+	}
+	return requests
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,134 @@
+// Copyright (c) Facebook, Inc. and its affiliates. All Rights Reserved
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package presto
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// NodeInfo describes the server, as reported by its /v1/info endpoint.
+type NodeInfo struct {
+	NodeVersion string
+	Environment string
+	Coordinator bool
+	Starting    bool // The server is starting and doesn't accept queries yet
+	Uptime      time.Duration
+}
+
+// ErrServerStarting indicates that the server is starting and doesn't accept
+// queries yet.
+var ErrServerStarting = errors.New("presto: server is starting")
+
+var _ driver.Pinger = &Conn{}
+
+// ServerInfo returns information about the server, such as its version,
+// without running a query.
+func ServerInfo(ctx context.Context, db *sql.DB) (*NodeInfo, error) {
+	var info *NodeInfo
+	err := withConn(ctx, db, func(c *Conn) error {
+		var err error
+		info, err = c.serverInfo(ctx, c.coordinator())
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+// Ping implements the driver.Pinger interface.
+//
+// It checks that the server is up and ready to run queries, without running
+// a query.
+func (c *Conn) Ping(ctx context.Context) error {
+	if c.closed.Load() {
+		return driver.ErrBadConn
+	}
+	return c.checkHealth(ctx, c.coordinator())
+}
+
+// serverInfo gets the information of a server from its /v1/info endpoint.
+func (c *Conn) serverInfo(ctx context.Context, baseURL string) (*NodeInfo, error) {
+	req, err := c.newRequest("GET", baseURL+"/v1/info", nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.roundTrip(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	var info struct {
+		NodeVersion struct {
+			Version string `json:"version"`
+		} `json:"nodeVersion"`
+		Environment string `json:"environment"`
+		Coordinator bool   `json:"coordinator"`
+		Starting    bool   `json:"starting"`
+		Uptime      string `json:"uptime"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&info); err != nil {
+		return nil, fmt.Errorf("presto: %w", err)
+	}
+	uptime, err := parseDuration(info.Uptime)
+	if err != nil {
+		return nil, err
+	}
+	return &NodeInfo{
+		NodeVersion: info.NodeVersion.Version,
+		Environment: info.Environment,
+		Coordinator: info.Coordinator,
+		Starting:    info.Starting,
+		Uptime:      uptime,
+	}, nil
+}
+
+// durationUnits are the units of the durations reported by the server.
+var durationUnits = map[string]time.Duration{
+	"ns": time.Nanosecond,
+	"us": time.Microsecond,
+	"ms": time.Millisecond,
+	"s":  time.Second,
+	"m":  time.Minute,
+	"h":  time.Hour,
+	"d":  24 * time.Hour,
+}
+
+// parseDuration parses a duration reported by the server, such as 1.50m.
+// An empty string is a zero duration.
+func parseDuration(s string) (time.Duration, error) {
+	if s == "" {
+		return 0, nil
+	}
+	i := strings.IndexFunc(s, func(r rune) bool {
+		return (r < '0' || r > '9') && r != '.'
+	})
+	if i > 0 {
+		if unit, ok := durationUnits[strings.TrimSpace(s[i:])]; ok {
+			if v, err := strconv.ParseFloat(s[:i], 64); err == nil {
+				return time.Duration(v * float64(unit)), nil
+			}
+		}
+	}
+	return 0, fmt.Errorf("presto: malformed duration %q", s)
+}
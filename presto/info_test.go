@@ -0,0 +1,80 @@
+// Copyright (c) Facebook, Inc. and its affiliates. All Rights Reserved
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package presto
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseDuration(t *testing.T) {
+	for _, tt := range []struct {
+		s    string
+		want time.Duration
+	}{
+		{"", 0},
+		{"1.50m", 90 * time.Second},
+		{"12.00s", 12 * time.Second},
+		{"250.00ms", 250 * time.Millisecond},
+		{"3.00us", 3 * time.Microsecond},
+		{"7ns", 7},
+		{"2.00h", 2 * time.Hour},
+		{"1.00d", 24 * time.Hour},
+		{"1.00 m", time.Minute},
+	} {
+		got, err := parseDuration(tt.s)
+		require.NoError(t, err, tt.s)
+		assert.Equal(t, tt.want, got, tt.s)
+	}
+	for _, s := range []string{"1", "m", "1.00w", "1..0s", "-1s", " 1s"} {
+		_, err := parseDuration(s)
+		assert.Error(t, err, s)
+	}
+}
+
+func TestServerInfo(t *testing.T) {
+	s := newFakeServer(t)
+	db := s.open(t, "")
+
+	info, err := ServerInfo(context.Background(), db)
+	require.NoError(t, err)
+	assert.Equal(t, &NodeInfo{NodeVersion: "0.287", Environment: "test", Coordinator: true, Uptime: time.Minute}, info)
+	assert.NoError(t, db.PingContext(context.Background()))
+	assert.Empty(t, s.received("POST", "/v1/statement"))
+}
+
+func TestPingStartingServer(t *testing.T) {
+	s := newFakeServer(t)
+	s.handler = func(w http.ResponseWriter, r *http.Request) bool {
+		writeJSON(w, map[string]interface{}{
+			"nodeVersion": map[string]string{"version": "0.287"},
+			"coordinator": true,
+			"starting":    true,
+			"uptime":      "1.00s",
+		})
+		return true
+	}
+	db := s.open(t, "")
+
+	assert.ErrorIs(t, db.PingContext(context.Background()), ErrServerStarting)
+	info, err := ServerInfo(context.Background(), db)
+	require.NoError(t, err)
+	assert.True(t, info.Starting)
+}
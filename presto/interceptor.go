@@ -0,0 +1,84 @@
+// Copyright (c) Facebook, Inc. and its affiliates. All Rights Reserved
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package presto
+
+import (
+	"context"
+	"net/http"
+)
+
+// Interceptor sees the statements submitted by the connections of a
+// Connector and the responses of their queries. It can add headers to the
+// statements, rewrite them, e.g. to add a comment with a job ID, prevent
+// them from running, or record audit events.
+type Interceptor interface {
+	// BeforeStatement is called before a statement is submitted. It may
+	// modify the statement; returning an error prevents the statement from
+	// running, and the query fails with the error.
+	BeforeStatement(ctx context.Context, stmt *Statement) error
+	// AfterPage is called with every response of a query: the one returned
+	// when submitting it, then the ones polled from the next URIs, which
+	// are received in a separate goroutine.
+	AfterPage(ctx context.Context, page *Page)
+}
+
+// Statement is a statement about to be submitted.
+type Statement struct {
+	// Query is the text of the statement, with placeholders for the
+	// arguments, which are bound after the interceptors are called.
+	Query string
+	// Header holds the headers specific to the statement. The session
+	// headers, such as the user, catalog and schema, are added when sending
+	// the request, unless they are set here.
+	Header http.Header
+}
+
+// Page is a response of a query.
+type Page struct {
+	QueryId    string
+	Rows       int // Number of rows in the response
+	QueryStats stmtStats
+	Err        error // Error reported by the server, if the query failed
+}
+
+// WithInterceptors adds interceptors to the connector, which are called in
+// order.
+func WithInterceptors(interceptors ...Interceptor) ConnectorOption {
+	return func(c *Connector) {
+		c.interceptors = append(c.interceptors, interceptors...)
+	}
+}
+
+// beforeStatement passes a statement to the interceptors of the connection,
+// and returns the query to submit.
+func (c *Conn) beforeStatement(ctx context.Context, query string, hs http.Header) (string, error) {
+	if len(c.interceptors) == 0 {
+		return query, nil
+	}
+	stmt := &Statement{Query: query, Header: hs}
+	for _, interceptor := range c.interceptors {
+		if err := interceptor.BeforeStatement(ctx, stmt); err != nil {
+			return "", err
+		}
+	}
+	return stmt.Query, nil
+}
+
+// afterPage passes a response to the interceptors of the connection.
+func (c *Conn) afterPage(ctx context.Context, page *Page) {
+	for _, interceptor := range c.interceptors {
+		interceptor.AfterPage(ctx, page)
+	}
+}
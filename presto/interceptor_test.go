@@ -0,0 +1,78 @@
+// Copyright (c) Facebook, Inc. and its affiliates. All Rights Reserved
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package presto
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var errForbidden = errors.New("forbidden")
+
+// jobInterceptor tags the statements with a job ID and rejects DROP
+// statements.
+type jobInterceptor struct {
+	mu    sync.Mutex
+	pages []Page
+}
+
+func (i *jobInterceptor) BeforeStatement(ctx context.Context, stmt *Statement) error {
+	if strings.HasPrefix(stmt.Query, "DROP ") {
+		return errForbidden
+	}
+	stmt.Query = "/* job 42 */ " + stmt.Query
+	stmt.Header.Set(prestoSourceHeader, "job-42")
+	return nil
+}
+
+func (i *jobInterceptor) AfterPage(ctx context.Context, page *Page) {
+	i.mu.Lock()
+	i.pages = append(i.pages, *page)
+	i.mu.Unlock()
+}
+
+func TestInterceptors(t *testing.T) {
+	s := newFakeServer(t)
+	interceptor := &jobInterceptor{}
+	connector, err := NewConnector(s.dsn(""), WithInterceptors(interceptor))
+	require.NoError(t, err)
+	db := sql.OpenDB(connector)
+	defer db.Close()
+
+	var x int64
+	require.NoError(t, db.QueryRow("SELECT ?", 1).Scan(&x))
+	posts := s.received("POST", "/v1/statement")
+	require.Len(t, posts, 1)
+	assert.Equal(t, "job-42", posts[0].Header.Get(prestoSourceHeader))
+	assert.Equal(t, []string{preparedStatementName + "=%2F%2A+job+42+%2A%2F+SELECT+%3F"}, posts[0].Header.Values(preparedStatementHeader),
+		"the arguments must be bound to the rewritten query")
+
+	interceptor.mu.Lock()
+	require.Len(t, interceptor.pages, 2)
+	assert.Equal(t, "q0", interceptor.pages[0].QueryId)
+	assert.Equal(t, 1, interceptor.pages[1].Rows)
+	interceptor.mu.Unlock()
+
+	_, err = db.Exec("DROP TABLE t")
+	assert.ErrorIs(t, err, errForbidden)
+	assert.Len(t, s.received("POST", "/v1/statement"), 1, "a rejected statement must not be sent")
+}
@@ -0,0 +1,205 @@
+// Copyright (c) Facebook, Inc. and its affiliates. All Rights Reserved
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package presto
+
+import (
+	"fmt"
+	"strings"
+)
+
+type sqlTokenKind int
+
+const (
+	sqlWord             sqlTokenKind = iota // keyword, identifier or number
+	sqlSpace                                // spaces and line breaks
+	sqlString                               // string literal, quotes included
+	sqlQuotedIdentifier                     // quoted identifier, quotes included
+	sqlComment                              // -- or /* */ comment
+	sqlPlaceholder                          // ? placeholder
+	sqlNamedPlaceholder                     // @name or :name placeholder
+	sqlSymbol                               // any other character
+)
+
+// sqlToken is a token of a SQL query. Concatenating the text of all the
+// tokens of a query returns the query.
+type sqlToken struct {
+	kind sqlTokenKind
+	text string
+	pos  int // byte offset of the token in the query
+}
+
+// sqlSyntaxError reports a query the lexer could not split into tokens.
+type sqlSyntaxError struct {
+	msg string
+	pos int
+}
+
+func (e *sqlSyntaxError) Error() string {
+	return fmt.Sprintf("presto: %s at offset %d", e.msg, e.pos)
+}
+
+func isSQLWordByte(c byte) bool {
+	return c == '_' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= 0x80
+}
+
+func isSQLNameStart(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+// lexSQL splits a query into tokens, so that the parts of the query inside
+// string literals, quoted identifiers and comments can be told apart.
+func lexSQL(query string) ([]sqlToken, error) {
+	var tokens []sqlToken
+	for pos := 0; pos < len(query); {
+		start := pos
+		kind := sqlSymbol
+		c := query[pos]
+		switch {
+		case c == '\'' || c == '"':
+			end, ok := quotedEnd(query, pos, c)
+			if !ok {
+				if c == '\'' {
+					return nil, &sqlSyntaxError{msg: "unterminated string literal", pos: pos}
+				}
+				return nil, &sqlSyntaxError{msg: "unterminated quoted identifier", pos: pos}
+			}
+			kind, pos = sqlString, end
+			if c == '"' {
+				kind = sqlQuotedIdentifier
+			}
+		case strings.HasPrefix(query[pos:], "--"):
+			kind = sqlComment
+			if i := strings.IndexByte(query[pos:], '\n'); i >= 0 {
+				pos += i
+			} else {
+				pos = len(query)
+			}
+		case strings.HasPrefix(query[pos:], "/*"):
+			i := strings.Index(query[pos+2:], "*/")
+			if i < 0 {
+				return nil, &sqlSyntaxError{msg: "unterminated comment", pos: pos}
+			}
+			kind, pos = sqlComment, pos+2+i+2
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f':
+			kind = sqlSpace
+			for pos < len(query) && strings.IndexByte(" \t\n\r\f", query[pos]) >= 0 {
+				pos++
+			}
+		case isSQLWordByte(c):
+			kind = sqlWord
+			for pos < len(query) && isSQLWordByte(query[pos]) {
+				pos++
+			}
+		case c == '?':
+			kind, pos = sqlPlaceholder, pos+1
+		case (c == '@' || c == ':') && pos+1 < len(query) && isSQLNameStart(query[pos+1]):
+			kind, pos = sqlNamedPlaceholder, pos+1
+			for pos < len(query) && isSQLWordByte(query[pos]) {
+				pos++
+			}
+		default:
+			pos++
+		}
+		tokens = append(tokens, sqlToken{kind: kind, text: query[start:pos], pos: start})
+	}
+	return tokens, nil
+}
+
+// quotedEnd returns the offset following the closing quote of the string
+// or identifier starting at pos, where a doubled quote is an escaped quote.
+func quotedEnd(query string, pos int, quote byte) (int, bool) {
+	for i := pos + 1; i < len(query); i++ {
+		if query[i] != quote {
+			continue
+		}
+		if i+1 < len(query) && query[i+1] == quote {
+			i++
+			continue
+		}
+		return i + 1, true
+	}
+	return 0, false
+}
+
+// interpolateParams replaces the ? placeholders of a query with args, which
+// are serialized values, in order.
+func interpolateParams(query string, args []string) (string, error) {
+	tokens, err := lexSQL(query)
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	n := 0
+	for _, t := range tokens {
+		if t.kind != sqlPlaceholder {
+			b.WriteString(t.text)
+			continue
+		}
+		if n == len(args) {
+			return "", fmt.Errorf("presto: query has more placeholders than the %d arguments", len(args))
+		}
+		b.WriteString(args[n])
+		n++
+	}
+	if n != len(args) {
+		return "", fmt.Errorf("presto: query has %d placeholders but %d arguments", n, len(args))
+	}
+	return b.String(), nil
+}
+
+type namedParam struct {
+	name  string
+	value string // serialized value
+}
+
+// bindNamedParams replaces the @name and :name placeholders of a query with
+// ? placeholders, and returns the values of the named parameters in the
+// order of the placeholders. A name can be used by several placeholders.
+func bindNamedParams(query string, params []namedParam) (string, []string, error) {
+	tokens, err := lexSQL(query)
+	if err != nil {
+		return "", nil, err
+	}
+	values := make(map[string]string, len(params))
+	for _, p := range params {
+		if _, ok := values[p.name]; ok {
+			return "", nil, fmt.Errorf("presto: named argument %q given more than once", p.name)
+		}
+		values[p.name] = p.value
+	}
+	used := make(map[string]bool, len(params))
+	var b strings.Builder
+	var args []string
+	for _, t := range tokens {
+		if t.kind != sqlNamedPlaceholder {
+			b.WriteString(t.text)
+			continue
+		}
+		name := t.text[1:]
+		v, ok := values[name]
+		if !ok {
+			return "", nil, fmt.Errorf("presto: missing named argument for placeholder %s at offset %d", t.text, t.pos)
+		}
+		used[name] = true
+		b.WriteString("?")
+		args = append(args, v)
+	}
+	for _, p := range params {
+		if !used[p.name] {
+			return "", nil, fmt.Errorf("presto: named argument %q not used in query", p.name)
+		}
+	}
+	return b.String(), args, nil
+}
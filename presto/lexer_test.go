@@ -0,0 +1,187 @@
+// Copyright (c) Facebook, Inc. and its affiliates. All Rights Reserved
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package presto
+
+import (
+	"database/sql"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLexSQL(t *testing.T) {
+	for _, tt := range []struct {
+		query string
+		kinds []sqlTokenKind
+	}{
+		{"SELECT ?", []sqlTokenKind{sqlWord, sqlSpace, sqlPlaceholder}},
+		{"'it''s ?'", []sqlTokenKind{sqlString}},
+		{`"a""?"`, []sqlTokenKind{sqlQuotedIdentifier}},
+		{"-- ?\n?", []sqlTokenKind{sqlComment, sqlSpace, sqlPlaceholder}},
+		{"/* ? */?", []sqlTokenKind{sqlComment, sqlPlaceholder}},
+		{"a=@id", []sqlTokenKind{sqlWord, sqlSymbol, sqlNamedPlaceholder}},
+		{":name,@1", []sqlTokenKind{sqlNamedPlaceholder, sqlSymbol, sqlSymbol, sqlWord}},
+		{"x > 1.5", []sqlTokenKind{sqlWord, sqlSpace, sqlSymbol, sqlSpace, sqlWord, sqlSymbol, sqlWord}},
+	} {
+		t.Run(tt.query, func(t *testing.T) {
+			tokens, err := lexSQL(tt.query)
+			require.NoError(t, err)
+			var kinds []sqlTokenKind
+			var text strings.Builder
+			for _, token := range tokens {
+				kinds = append(kinds, token.kind)
+				assert.Equal(t, token.text, tt.query[token.pos:token.pos+len(token.text)])
+				text.WriteString(token.text)
+			}
+			assert.Equal(t, tt.kinds, kinds)
+			assert.Equal(t, tt.query, text.String(), "the tokens must add up to the query")
+		})
+	}
+}
+
+func TestLexSQLErrors(t *testing.T) {
+	for _, tt := range []struct {
+		query string
+		want  string
+	}{
+		{"SELECT 'abc", "presto: unterminated string literal at offset 7"},
+		{`SELECT "abc`, "presto: unterminated quoted identifier at offset 7"},
+		{"SELECT /* abc", "presto: unterminated comment at offset 7"},
+	} {
+		t.Run(tt.query, func(t *testing.T) {
+			_, err := lexSQL(tt.query)
+			assert.EqualError(t, err, tt.want)
+		})
+	}
+}
+
+func TestInterpolateParams(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		query   string
+		args    []string
+		want    string
+		wantErr string
+	}{
+		{"none", "SELECT 1", nil, "SELECT 1", ""},
+		{"in order", "SELECT ? + ?", []string{"1", "2"}, "SELECT 1 + 2", ""},
+		{"quoted", "SELECT '?', \"?\", ? -- ?", []string{"'x'"}, "SELECT '?', \"?\", 'x' -- ?", ""},
+		{"too few placeholders", "SELECT ?", []string{"1", "2"}, "", "presto: query has 1 placeholders but 2 arguments"},
+		{"too many placeholders", "SELECT ?, ?", []string{"1"}, "", "presto: query has more placeholders than the 1 arguments"},
+		{"syntax error", "SELECT '?", []string{"1"}, "", "presto: unterminated string literal at offset 7"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := interpolateParams(tt.query, tt.args)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestInterpolateParamsMode(t *testing.T) {
+	s := newFakeServer(t)
+	db := s.open(t, "interpolate_params=true")
+
+	_, err := db.Exec("SELECT * FROM t WHERE a = ? AND b = '?' AND c = ?", "it's", 42)
+	require.NoError(t, err)
+	posts := s.received("POST", "/v1/statement")
+	require.Len(t, posts, 1)
+	assert.Equal(t, "SELECT * FROM t WHERE a = 'it''s' AND b = '?' AND c = 42", posts[0].Body)
+	assert.Empty(t, posts[0].Header.Values(preparedStatementHeader))
+}
+
+func TestBindNamedParams(t *testing.T) {
+	for _, tt := range []struct {
+		name     string
+		query    string
+		params   []namedParam
+		want     string
+		wantArgs []string
+		wantErr  string
+	}{
+		{
+			name:     "at and colon",
+			query:    "SELECT * FROM t WHERE a = @a AND b = :b",
+			params:   []namedParam{{"b", "2"}, {"a", "1"}},
+			want:     "SELECT * FROM t WHERE a = ? AND b = ?",
+			wantArgs: []string{"1", "2"},
+		},
+		{
+			name:     "reused",
+			query:    "SELECT @x, @x",
+			params:   []namedParam{{"x", "'v'"}},
+			want:     "SELECT ?, ?",
+			wantArgs: []string{"'v'", "'v'"},
+		},
+		{
+			name:     "quoted",
+			query:    "SELECT ':a', \"@a\", @a /* :a */",
+			params:   []namedParam{{"a", "1"}},
+			want:     "SELECT ':a', \"@a\", ? /* :a */",
+			wantArgs: []string{"1"},
+		},
+		{
+			name:    "missing",
+			query:   "SELECT @a, @b",
+			params:  []namedParam{{"a", "1"}},
+			wantErr: "presto: missing named argument for placeholder @b at offset 11",
+		},
+		{
+			name:    "unused",
+			query:   "SELECT @a",
+			params:  []namedParam{{"a", "1"}, {"b", "2"}},
+			wantErr: `presto: named argument "b" not used in query`,
+		},
+		{
+			name:    "duplicate",
+			query:   "SELECT @a",
+			params:  []namedParam{{"a", "1"}, {"a", "2"}},
+			wantErr: `presto: named argument "a" given more than once`,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			got, args, err := bindNamedParams(tt.query, tt.params)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.wantArgs, args)
+		})
+	}
+}
+
+func TestNamedArguments(t *testing.T) {
+	s := newFakeServer(t)
+	db := s.open(t, "")
+
+	_, err := db.Exec("SELECT * FROM t WHERE a = :a AND b = @b", sql.Named("b", "x"), sql.Named("a", 1))
+	require.NoError(t, err)
+	posts := s.received("POST", "/v1/statement")
+	require.Len(t, posts, 1)
+	assert.Equal(t, "EXECUTE "+preparedStatementName+" USING 1, 'x'", posts[0].Body)
+	assert.Equal(t, []string{preparedStatementName + "=" + url.QueryEscape("SELECT * FROM t WHERE a = ? AND b = ?")}, posts[0].Header.Values(preparedStatementHeader))
+
+	_, err = db.Exec("SELECT @a, ?", sql.Named("a", 1), 2)
+	assert.ErrorIs(t, err, ErrMixedParams)
+}
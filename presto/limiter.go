@@ -0,0 +1,138 @@
+// Copyright (c) Facebook, Inc. and its affiliates. All Rights Reserved
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package presto
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// QueryPriority is the priority of a query waiting for one of the slots set
+// with max_concurrent_queries in the DSN. It is passed to a query as a named
+// argument:
+//
+//	db.QueryContext(ctx, query, sql.Named("X-Presto-Query-Priority", presto.PriorityHigh))
+//
+// Waiting queries get a slot in order of priority, then in order of arrival.
+type QueryPriority int
+
+// Query priorities.
+const (
+	PriorityLow QueryPriority = iota
+	PriorityNormal
+	PriorityHigh
+
+	numPriorities = int(PriorityHigh) + 1
+)
+
+// queryLimiter limits the number of queries running at once. It is shared
+// by the connections of a Connector.
+type queryLimiter struct {
+	mu      sync.Mutex
+	limit   int
+	running int
+	waiting [numPriorities][]chan struct{}
+}
+
+// newQueryLimiter returns the limiter set with max_concurrent_queries in a
+// DSN, or nil if there is no limit.
+func newQueryLimiter(value string) (*queryLimiter, error) {
+	if value == "" {
+		return nil, nil
+	}
+	limit, err := strconv.Atoi(value)
+	if err != nil || limit < 0 {
+		return nil, fmt.Errorf("presto: malformed max_concurrent_queries %q", value)
+	}
+	if limit == 0 {
+		return nil, nil
+	}
+	return &queryLimiter{limit: limit}, nil
+}
+
+// acquire waits for a slot to run a query, and returns the time it waited.
+// The slot must be released once the query is done.
+func (l *queryLimiter) acquire(ctx context.Context, priority QueryPriority) (time.Duration, error) {
+	if priority < PriorityLow || priority > PriorityHigh {
+		return 0, fmt.Errorf("presto: invalid query priority %d", priority)
+	}
+	l.mu.Lock()
+	if l.running < l.limit && l.numWaiting() == 0 {
+		l.running++
+		l.mu.Unlock()
+		return 0, nil
+	}
+	start := time.Now()
+	ready := make(chan struct{})
+	l.waiting[priority] = append(l.waiting[priority], ready)
+	l.mu.Unlock()
+	select {
+	case <-ready:
+		return time.Since(start), nil
+	case <-ctx.Done():
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		select {
+		case <-ready:
+			// The slot was handed over while giving up: pass it on.
+			l.handOver()
+		default:
+			l.remove(priority, ready)
+		}
+		return time.Since(start), ctx.Err()
+	}
+}
+
+// release releases a slot acquired for a query.
+func (l *queryLimiter) release() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.handOver()
+}
+
+// handOver passes a released slot to the first waiting query of the highest
+// priority, if any.
+func (l *queryLimiter) handOver() {
+	for p := numPriorities - 1; p >= 0; p-- {
+		if len(l.waiting[p]) > 0 {
+			ready := l.waiting[p][0]
+			l.waiting[p] = l.waiting[p][1:]
+			close(ready)
+			return
+		}
+	}
+	l.running--
+}
+
+func (l *queryLimiter) numWaiting() int {
+	n := 0
+	for _, w := range l.waiting {
+		n += len(w)
+	}
+	return n
+}
+
+func (l *queryLimiter) remove(priority QueryPriority, ready chan struct{}) {
+	w := l.waiting[priority]
+	for i, c := range w {
+		if c == ready {
+			l.waiting[priority] = append(w[:i:i], w[i+1:]...)
+			return
+		}
+	}
+}
@@ -0,0 +1,148 @@
+// Copyright (c) Facebook, Inc. and its affiliates. All Rights Reserved
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package presto
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewQueryLimiter(t *testing.T) {
+	for _, value := range []string{"", "0"} {
+		l, err := newQueryLimiter(value)
+		require.NoError(t, err, value)
+		assert.Nil(t, l, value)
+	}
+	l, err := newQueryLimiter("2")
+	require.NoError(t, err)
+	assert.Equal(t, 2, l.limit)
+	for _, value := range []string{"-1", "x", "1.5"} {
+		_, err := newQueryLimiter(value)
+		assert.Error(t, err, value)
+	}
+}
+
+// waitQueued waits until n queries wait for a slot of l.
+func waitQueued(t *testing.T, l *queryLimiter, n int) {
+	require.Eventually(t, func() bool {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		return l.numWaiting() == n
+	}, time.Second, time.Millisecond)
+}
+
+func TestQueryLimiterPriority(t *testing.T) {
+	l := &queryLimiter{limit: 1}
+	ctx := context.Background()
+	_, err := l.acquire(ctx, PriorityNormal)
+	require.NoError(t, err)
+
+	acquired := make(chan string)
+	for i, q := range []struct {
+		name     string
+		priority QueryPriority
+	}{
+		{"low", PriorityLow},
+		{"normal 1", PriorityNormal},
+		{"high", PriorityHigh},
+		{"normal 2", PriorityNormal},
+	} {
+		q := q
+		go func() {
+			_, err := l.acquire(ctx, q.priority)
+			assert.NoError(t, err)
+			acquired <- q.name
+		}()
+		waitQueued(t, l, i+1)
+	}
+	for _, want := range []string{"high", "normal 1", "normal 2", "low"} {
+		l.release()
+		assert.Equal(t, want, <-acquired)
+	}
+	l.release()
+	assert.Equal(t, 0, l.running)
+
+	_, err = l.acquire(ctx, PriorityHigh+1)
+	assert.Error(t, err)
+}
+
+func TestQueryLimiterCancel(t *testing.T) {
+	l := &queryLimiter{limit: 1}
+	_, err := l.acquire(context.Background(), PriorityNormal)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errs := make(chan error)
+	go func() {
+		_, err := l.acquire(ctx, PriorityHigh)
+		errs <- err
+	}()
+	waitQueued(t, l, 1)
+	cancel()
+	assert.ErrorIs(t, <-errs, context.Canceled)
+	waitQueued(t, l, 0)
+
+	l.release()
+	assert.Equal(t, 0, l.running)
+}
+
+func TestAdmissionQueue(t *testing.T) {
+	s := newFakeServer(t)
+	s.result = func(query string, header http.Header) fakeResult {
+		return fakeResult{Columns: []string{"_col0"}, Pages: [][]queryData{{{1}}, {{2}}}}
+	}
+	for _, tt := range []struct {
+		name   string
+		queued int
+		wrap   func(m *recordingMetrics) Metrics
+	}{
+		{"queue metrics", 2, func(m *recordingMetrics) Metrics { return m }},
+		{"metrics", 0, func(m *recordingMetrics) Metrics { return struct{ Metrics }{m} }},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			metrics := &recordingMetrics{}
+			connector, err := NewConnector(s.dsn("max_concurrent_queries=1"), WithMetrics(tt.wrap(metrics)))
+			require.NoError(t, err)
+			db := sql.OpenDB(connector)
+			defer db.Close()
+
+			rows, err := db.Query("SELECT 1")
+			require.NoError(t, err)
+			done := make(chan error)
+			go func() {
+				var n int
+				done <- db.QueryRow("SELECT 2", sql.Named("X-Presto-Query-Priority", PriorityHigh)).Scan(&n)
+			}()
+			waitQueued(t, connector.limiter, 1)
+			select {
+			case err := <-done:
+				t.Fatalf("query ran without a slot: %v", err)
+			case <-time.After(50 * time.Millisecond):
+			}
+			require.NoError(t, rows.Close())
+			require.NoError(t, <-done)
+
+			metrics.mu.Lock()
+			defer metrics.mu.Unlock()
+			assert.Equal(t, tt.queued, metrics.queued)
+		})
+	}
+}
@@ -0,0 +1,100 @@
+// Copyright (c) Facebook, Inc. and its affiliates. All Rights Reserved
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package presto
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// redactedHeaders are the headers whose values are never logged.
+var redactedHeaders = []string{
+	"Authorization",
+	"Proxy-Authorization",
+	prestoExtraCredentialHeader,
+}
+
+// sessionResponseHeaders are the response headers changing the session state.
+var sessionResponseHeaders = []string{
+	prestoSetCatalogHeader,
+	prestoSetSchemaHeader,
+	prestoSetPathHeader,
+	prestoSetSessionHeader,
+	prestoClearSessionHeader,
+	prestoSetRoleHeader,
+	prestoAddedPrepareHeader,
+	prestoDeallocatedPrepareHeader,
+}
+
+// WithLogger logs the requests sent by the connections of the connector, the
+// session state changes, the state transitions of the queries and the
+// retries, at debug level. The values of the Authorization and
+// X-Presto-Extra-Credential headers are redacted.
+func WithLogger(logger *slog.Logger) ConnectorOption {
+	return func(c *Connector) {
+		c.logger = logger
+	}
+}
+
+// logHeaders is an http.Header logged as a group of attributes, with the
+// values of sensitive headers redacted.
+type logHeaders http.Header
+
+// LogValue implements the slog.LogValuer interface.
+func (h logHeaders) LogValue() slog.Value {
+	keys := make([]string, 0, len(h))
+	for k := range h {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	attrs := make([]slog.Attr, 0, len(keys))
+	for _, k := range keys {
+		v := strings.Join(h[k], ", ")
+		for _, redacted := range redactedHeaders {
+			if http.CanonicalHeaderKey(k) == http.CanonicalHeaderKey(redacted) {
+				v = "REDACTED"
+				break
+			}
+		}
+		attrs = append(attrs, slog.String(k, v))
+	}
+	return slog.GroupValue(attrs...)
+}
+
+// debug logs a debug event if the connection has a logger.
+func (c *Conn) debug(ctx context.Context, msg string, args ...any) {
+	if c.logger != nil {
+		c.logger.DebugContext(ctx, msg, args...)
+	}
+}
+
+// logSessionChanges logs the session state changes sent by the server.
+func (c *Conn) logSessionChanges(ctx context.Context, resp http.Header) {
+	if c.logger == nil {
+		return
+	}
+	changes := make(http.Header)
+	for _, k := range sessionResponseHeaders {
+		if v := resp.Values(k); len(v) > 0 {
+			changes[k] = v
+		}
+	}
+	if len(changes) > 0 {
+		c.debug(ctx, "presto: session state changed", "headers", logHeaders(changes))
+	}
+}
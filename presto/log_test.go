@@ -0,0 +1,60 @@
+// Copyright (c) Facebook, Inc. and its affiliates. All Rights Reserved
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package presto
+
+import (
+	"bytes"
+	"database/sql"
+	"log/slog"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLogger(t *testing.T) {
+	s := newFakeServer(t)
+	s.result = func(string, http.Header) fakeResult {
+		result := fakeRows
+		result.Header = http.Header{prestoSetSchemaHeader: {"changed"}}
+		return result
+	}
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	connector, err := NewConnector(s.dsn("extra_credentials=token%3Dsecret"), WithLogger(logger))
+	require.NoError(t, err)
+	db := sql.OpenDB(connector)
+	defer db.Close()
+
+	_, err = db.Exec("SELECT 1")
+	require.NoError(t, err)
+
+	out := buf.String()
+	assert.Contains(t, out, `msg="presto: sending request"`)
+	assert.Contains(t, out, `msg="presto: session state changed" headers.X-Presto-Set-Schema=changed`)
+	assert.Contains(t, out, `msg="presto: query done"`)
+	assert.Contains(t, out, "headers.X-Presto-Extra-Credential=REDACTED")
+	assert.NotContains(t, out, "secret")
+}
+
+func TestLogHeaders(t *testing.T) {
+	v := logHeaders(http.Header{
+		"Authorization": {"Basic abc"},
+		"X-Presto-User": {"user"},
+		"Accept":        {"a", "b"},
+	}).LogValue()
+	assert.Equal(t, "[Accept=a, b Authorization=REDACTED X-Presto-User=user]", v.String())
+}
@@ -0,0 +1,230 @@
+// Copyright (c) Facebook, Inc. and its affiliates. All Rights Reserved
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package presto
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+
+	"github.com/timescale/presto-go-client/presto/types"
+)
+
+// Metadata lists the catalogs, schemas, tables and columns known to the
+// server.
+//
+// The like arguments of its methods are LIKE patterns filtering the results
+// by name, e.g. "sales_%"; an empty pattern matches everything. An empty
+// catalog refers to the catalog of the session.
+type Metadata struct {
+	db queryer
+}
+
+// NewMetadata returns a Metadata running its queries on db, which is usually
+// a *sql.DB, but can also be a *sql.Conn or a *sql.Tx.
+func NewMetadata(db queryer) *Metadata {
+	return &Metadata{db: db}
+}
+
+// Catalog is a catalog of the server.
+type Catalog struct {
+	Name string
+}
+
+// Schema is a schema of a catalog.
+type Schema struct {
+	Catalog string
+	Name    string
+}
+
+// Table is a table or a view.
+type Table struct {
+	Catalog string
+	Schema  string
+	Name    string
+	Type    string // BASE TABLE or VIEW
+}
+
+// TableColumn is a column of a table or a view.
+type TableColumn struct {
+	Catalog       string
+	Schema        string
+	Table         string
+	Name          string
+	Position      int           // Position of the column in the table, starting at 1
+	Type          string        // Type as text, e.g. varchar(10)
+	TypeSignature TypeSignature // Parsed type, the zero TypeSignature if the type can't be parsed
+	Nullable      bool
+	Comment       string
+
+	converter *typeConverter
+}
+
+// Length returns the length of variable length types, such as varchar(10).
+func (c *TableColumn) Length() (length int64, ok bool) {
+	if c.converter == nil {
+		return 0, false
+	}
+	return c.converter.size.value, c.converter.size.hasValue
+}
+
+// PrecisionScale returns the precision and scale of decimal types, and the
+// precision of time and timestamp types.
+func (c *TableColumn) PrecisionScale() (precision, scale int64, ok bool) {
+	if c.converter == nil {
+		return 0, 0, false
+	}
+	return c.converter.precision.value, c.converter.scale.value, c.converter.precision.hasValue
+}
+
+// Catalogs returns the catalogs matching like.
+func (m *Metadata) Catalogs(ctx context.Context, like string) ([]Catalog, error) {
+	query := "SHOW CATALOGS"
+	if like != "" {
+		// SHOW statements can't be prepared, so the pattern is inlined.
+		pattern, err := Serial(like)
+		if err != nil {
+			return nil, err
+		}
+		query += " LIKE " + pattern
+	}
+	rows, err := m.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var catalogs []Catalog
+	for rows.Next() {
+		var c Catalog
+		if err := rows.Scan(&c.Name); err != nil {
+			return nil, err
+		}
+		catalogs = append(catalogs, c)
+	}
+	return catalogs, rows.Err()
+}
+
+// Schemas returns the schemas of a catalog matching like.
+func (m *Metadata) Schemas(ctx context.Context, catalog, like string) ([]Schema, error) {
+	query, args := metadataQuery(
+		"SELECT catalog_name, schema_name FROM "+informationSchema(catalog, "schemata"),
+		"schema_name", like,
+	)
+	rows, err := m.db.QueryContext(ctx, query+" ORDER BY schema_name", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var schemas []Schema
+	for rows.Next() {
+		var s Schema
+		if err := rows.Scan(&s.Catalog, &s.Name); err != nil {
+			return nil, err
+		}
+		schemas = append(schemas, s)
+	}
+	return schemas, rows.Err()
+}
+
+// Tables returns the tables and views of a catalog whose schema matches
+// schemaLike and whose name matches tableLike.
+func (m *Metadata) Tables(ctx context.Context, catalog, schemaLike, tableLike string) ([]Table, error) {
+	query, args := metadataQuery(
+		"SELECT table_catalog, table_schema, table_name, table_type FROM "+informationSchema(catalog, "tables"),
+		"table_schema", schemaLike,
+		"table_name", tableLike,
+	)
+	rows, err := m.db.QueryContext(ctx, query+" ORDER BY table_schema, table_name", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var tables []Table
+	for rows.Next() {
+		var t Table
+		if err := rows.Scan(&t.Catalog, &t.Schema, &t.Name, &t.Type); err != nil {
+			return nil, err
+		}
+		tables = append(tables, t)
+	}
+	return tables, rows.Err()
+}
+
+// Columns returns the columns of the tables and views of a catalog whose
+// schema matches schemaLike, whose table matches tableLike and whose name
+// matches columnLike, ordered by table and position.
+func (m *Metadata) Columns(ctx context.Context, catalog, schemaLike, tableLike, columnLike string) ([]TableColumn, error) {
+	query, args := metadataQuery(
+		"SELECT table_catalog, table_schema, table_name, column_name, ordinal_position, data_type, is_nullable, comment FROM "+informationSchema(catalog, "columns"),
+		"table_schema", schemaLike,
+		"table_name", tableLike,
+		"column_name", columnLike,
+	)
+	rows, err := m.db.QueryContext(ctx, query+" ORDER BY table_schema, table_name, ordinal_position", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var columns []TableColumn
+	for rows.Next() {
+		var c TableColumn
+		var nullable string
+		var comment sql.NullString
+		if err := rows.Scan(&c.Catalog, &c.Schema, &c.Table, &c.Name, &c.Position, &c.Type, &nullable, &comment); err != nil {
+			return nil, err
+		}
+		c.Nullable = nullable == "YES"
+		c.Comment = comment.String
+		// Types the driver doesn't know, e.g. ones added by a connector, are
+		// only described by their text.
+		if sig, err := types.Parse(c.Type); err == nil {
+			c.TypeSignature = sig
+			c.converter, _ = newTypeConverter(c.Type, sig)
+		}
+		columns = append(columns, c)
+	}
+	return columns, rows.Err()
+}
+
+// informationSchema returns the name of a table of the information schema of
+// a catalog.
+func informationSchema(catalog, table string) string {
+	if catalog == "" {
+		return "information_schema." + table
+	}
+	return quoteIdentifier(catalog) + ".information_schema." + table
+}
+
+// metadataQuery adds a LIKE condition to query for each pair of column and
+// non-empty pattern in filters.
+func metadataQuery(query string, filters ...string) (string, []interface{}) {
+	var conditions []string
+	var args []interface{}
+	for i := 0; i+1 < len(filters); i += 2 {
+		if filters[i+1] == "" {
+			continue
+		}
+		conditions = append(conditions, filters[i]+" LIKE ?")
+		args = append(args, filters[i+1])
+	}
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	return query, args
+}
+
+func quoteIdentifier(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
@@ -0,0 +1,80 @@
+// Copyright (c) Facebook, Inc. and its affiliates. All Rights Reserved
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package presto
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetadataColumns(t *testing.T) {
+	s := newFakeServer(t)
+	s.result = func(string, http.Header) fakeResult {
+		return fakeResult{
+			Columns: []string{"table_catalog", "table_schema", "table_name", "column_name", "ordinal_position", "data_type", "is_nullable", "comment"},
+			Types:   []string{"varchar", "varchar", "varchar", "varchar", "bigint", "varchar", "varchar", "varchar"},
+			Pages: [][]queryData{{
+				{"hive", "s", "t", "name", 1, "varchar(10)", "YES", "a name"},
+				{"hive", "s", "t", "odd", 2, `"unparsable`, "NO", nil},
+			}},
+		}
+	}
+	db := s.open(t, "")
+
+	columns, err := NewMetadata(db).Columns(context.Background(), "hive", "s", "t%", "")
+	require.NoError(t, err)
+	require.Len(t, columns, 2)
+	length, ok := columns[0].Length()
+	assert.True(t, ok)
+	assert.EqualValues(t, 10, length)
+	assert.Equal(t, "varchar", columns[0].TypeSignature.RawType)
+	assert.True(t, columns[0].Nullable)
+	assert.Equal(t, "a name", columns[0].Comment)
+
+	assert.Equal(t, `"unparsable`, columns[1].Type, "columns with an unknown type must keep their type text")
+	assert.Equal(t, TypeSignature{}, columns[1].TypeSignature)
+	_, ok = columns[1].Length()
+	assert.False(t, ok)
+
+	posts := s.received("POST", "/v1/statement")
+	require.Len(t, posts, 1)
+	assert.Equal(t, "EXECUTE "+preparedStatementName+" USING 's', 't%'", posts[0].Body)
+}
+
+func TestMetadataQuery(t *testing.T) {
+	for _, tt := range []struct {
+		name      string
+		filters   []string
+		wantQuery string
+		wantArgs  []interface{}
+	}{
+		{"none", nil, "SELECT x FROM t", nil},
+		{"empty patterns", []string{"a", "", "b", ""}, "SELECT x FROM t", nil},
+		{"one", []string{"a", "", "b", "p%"}, "SELECT x FROM t WHERE b LIKE ?", []interface{}{"p%"}},
+		{"several", []string{"a", "x", "b", "y"}, "SELECT x FROM t WHERE a LIKE ? AND b LIKE ?", []interface{}{"x", "y"}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			query, args := metadataQuery("SELECT x FROM t", tt.filters...)
+			assert.Equal(t, tt.wantQuery, query)
+			assert.Equal(t, tt.wantArgs, args)
+		})
+	}
+	assert.Equal(t, "information_schema.tables", informationSchema("", "tables"))
+	assert.Equal(t, `"my""catalog".information_schema.tables`, informationSchema(`my"catalog`, "tables"))
+}
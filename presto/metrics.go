@@ -0,0 +1,120 @@
+// Copyright (c) Facebook, Inc. and its affiliates. All Rights Reserved
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package presto
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Metrics receives measurements of the queries run by the connections of a
+// Connector, e.g. to export them to Prometheus with the prompresto package.
+// Its methods are called concurrently.
+type Metrics interface {
+	// QueryStarted is called when a query is submitted.
+	QueryStarted()
+	// QueryFailed is called when a query fails, with the name of the error
+	// reported by the server, such as SYNTAX_ERROR, or one of
+	// CONNECTION_ERROR, HTTP_<status code>, CLIENT_TIMEOUT and CLIENT_ERROR
+	// for the failures detected by the driver.
+	QueryFailed(errorName string)
+	// QueryCancelled is called when a query is cancelled, by the server,
+	// by cancelling its context, or by closing its rows before the end.
+	QueryCancelled()
+	// PageFetched is called for every response polled from the next URI of
+	// a query, with the time it took to receive it, not counting the time
+	// it waited for the client to read the previous pages, and its size.
+	PageFetched(latency time.Duration, bytes int64)
+	// RowsReturned is called with the number of rows of every page of
+	// results read by the client.
+	RowsReturned(rows int)
+	// RequestRetried is called when a request is retried because the server
+	// is busy.
+	RequestRetried(statusCode int)
+}
+
+// QueueMetrics is implemented by the Metrics which also measure the time
+// queries wait for a slot to run, when max_concurrent_queries is set in the
+// DSN.
+type QueueMetrics interface {
+	// QueryQueued is called with the time a query waited for a slot to run.
+	QueryQueued(wait time.Duration)
+}
+
+// WithMetrics reports measurements of the queries run by the connections of
+// the connector.
+func WithMetrics(metrics Metrics) ConnectorOption {
+	return func(c *Connector) {
+		c.metrics = metrics
+	}
+}
+
+// errorName returns the name of the error of a failed query.
+func errorName(err error) string {
+	var qferr *ErrQueryFailed
+	switch {
+	case errors.As(err, &qferr):
+		if reason, ok := qferr.Reason.(*stmtError); ok && reason.ErrorName != "" {
+			return reason.ErrorName
+		}
+		if qferr.StatusCode == 0 {
+			return "CONNECTION_ERROR"
+		}
+		return "HTTP_" + strconv.Itoa(qferr.StatusCode)
+	case errors.Is(err, context.DeadlineExceeded), err == ErrIdleTimeout:
+		return "CLIENT_TIMEOUT"
+	default:
+		return "CLIENT_ERROR"
+	}
+}
+
+// isCancellation reports whether err is the error of a cancelled query.
+func isCancellation(err error) bool {
+	return err == ErrQueryCancelled || errors.Is(err, context.Canceled)
+}
+
+// meteredBody counts the bytes read from the body of a response.
+type meteredBody struct {
+	io.ReadCloser
+	latency time.Duration
+	bytes   int64
+}
+
+func (b *meteredBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.bytes += int64(n)
+	return n, err
+}
+
+// meterBody prepares a response polled from a next URI, received after
+// latency, to report its size. The latency is measured by the caller as soon
+// as the response is received, so that it doesn't include the time the
+// response waits to be decoded.
+func (c *Conn) meterBody(resp *http.Response, latency time.Duration) {
+	if c.metrics != nil {
+		resp.Body = &meteredBody{ReadCloser: resp.Body, latency: latency}
+	}
+}
+
+// reportPage reports a response prepared with meterBody, once decoded.
+func (c *Conn) reportPage(resp *http.Response) {
+	if body, ok := resp.Body.(*meteredBody); ok {
+		c.metrics.PageFetched(body.latency, body.bytes)
+	}
+}
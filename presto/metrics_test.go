@@ -0,0 +1,173 @@
+// Copyright (c) Facebook, Inc. and its affiliates. All Rights Reserved
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package presto
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingMetrics counts the measurements it receives.
+type recordingMetrics struct {
+	mu        sync.Mutex
+	started   int
+	failed    []string
+	cancelled int
+	latencies []time.Duration
+	rows      int
+	retried   []int
+	queued    int
+}
+
+func (m *recordingMetrics) QueryStarted() {
+	m.mu.Lock()
+	m.started++
+	m.mu.Unlock()
+}
+
+func (m *recordingMetrics) QueryFailed(errorName string) {
+	m.mu.Lock()
+	m.failed = append(m.failed, errorName)
+	m.mu.Unlock()
+}
+
+func (m *recordingMetrics) QueryCancelled() {
+	m.mu.Lock()
+	m.cancelled++
+	m.mu.Unlock()
+}
+
+func (m *recordingMetrics) PageFetched(latency time.Duration, bytes int64) {
+	m.mu.Lock()
+	m.latencies = append(m.latencies, latency)
+	m.mu.Unlock()
+}
+
+func (m *recordingMetrics) RowsReturned(rows int) {
+	m.mu.Lock()
+	m.rows += rows
+	m.mu.Unlock()
+}
+
+func (m *recordingMetrics) RequestRetried(statusCode int) {
+	m.mu.Lock()
+	m.retried = append(m.retried, statusCode)
+	m.mu.Unlock()
+}
+
+func (m *recordingMetrics) QueryQueued(wait time.Duration) {
+	m.mu.Lock()
+	m.queued++
+	m.mu.Unlock()
+}
+
+func TestMetrics(t *testing.T) {
+	s := newFakeServer(t)
+	var busy atomic.Bool
+	busy.Store(true)
+	s.handler = func(w http.ResponseWriter, r *http.Request) bool {
+		if r.Method == "POST" && busy.CompareAndSwap(true, false) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return true
+		}
+		return false
+	}
+	s.result = func(query string, _ http.Header) fakeResult {
+		if query == "SELECT fail" {
+			return fakeResult{Columns: []string{"x"}, Error: &stmtError{Message: "failed", ErrorName: "SYNTAX_ERROR"}}
+		}
+		return fakeResult{Columns: []string{"x"}, Pages: [][]queryData{{{1}, {2}}, {{3}}}}
+	}
+	metrics := &recordingMetrics{}
+	connector, err := NewConnector(s.dsn(""), WithMetrics(metrics))
+	require.NoError(t, err)
+	db := sql.OpenDB(connector)
+	defer db.Close()
+
+	rows, err := db.Query("SELECT x")
+	require.NoError(t, err)
+	for rows.Next() {
+	}
+	require.NoError(t, rows.Err())
+	_, err = db.Exec("SELECT fail")
+	assert.Error(t, err)
+	rows, err = db.Query("SELECT x")
+	require.NoError(t, err)
+	require.NoError(t, rows.Close())
+
+	metrics.mu.Lock()
+	defer metrics.mu.Unlock()
+	assert.Equal(t, 3, metrics.started)
+	assert.Equal(t, []string{"SYNTAX_ERROR"}, metrics.failed)
+	assert.Equal(t, 1, metrics.cancelled)
+	assert.Equal(t, []int{http.StatusServiceUnavailable}, metrics.retried)
+	assert.GreaterOrEqual(t, len(metrics.latencies), 3)
+	assert.GreaterOrEqual(t, metrics.rows, 3)
+}
+
+func TestPageLatency(t *testing.T) {
+	s := newFakeServer(t)
+	s.result = func(string, http.Header) fakeResult {
+		return fakeResult{Columns: []string{"x"}, Pages: [][]queryData{{{1}}, {{2}}, {{3}}, {{4}}}}
+	}
+	metrics := &recordingMetrics{}
+	connector, err := NewConnector(s.dsn(""), WithMetrics(metrics))
+	require.NoError(t, err)
+	db := sql.OpenDB(connector)
+	defer db.Close()
+
+	// The time the pages wait for a slow client isn't part of the latency.
+	rows, err := db.Query("SELECT x")
+	require.NoError(t, err)
+	for rows.Next() {
+		time.Sleep(100 * time.Millisecond)
+	}
+	require.NoError(t, rows.Err())
+
+	metrics.mu.Lock()
+	defer metrics.mu.Unlock()
+	require.Len(t, metrics.latencies, 4)
+	for _, latency := range metrics.latencies {
+		assert.Less(t, latency, 100*time.Millisecond)
+	}
+}
+
+func TestErrorName(t *testing.T) {
+	for _, tt := range []struct {
+		err  error
+		want string
+	}{
+		{&ErrQueryFailed{StatusCode: 200, Reason: &stmtError{ErrorName: "SYNTAX_ERROR"}}, "SYNTAX_ERROR"},
+		{&ErrQueryFailed{Reason: errors.New("connection refused")}, "CONNECTION_ERROR"},
+		{&ErrQueryFailed{StatusCode: 502}, "HTTP_502"},
+		{fmt.Errorf("wrapped: %w", context.DeadlineExceeded), "CLIENT_TIMEOUT"},
+		{ErrIdleTimeout, "CLIENT_TIMEOUT"},
+		{errors.New("other"), "CLIENT_ERROR"},
+	} {
+		t.Run(tt.want, func(t *testing.T) {
+			assert.Equal(t, tt.want, errorName(tt.err))
+		})
+	}
+}
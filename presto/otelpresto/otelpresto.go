@@ -0,0 +1,197 @@
+// Copyright (c) Facebook, Inc. and its affiliates. All Rights Reserved
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package otelpresto traces the queries of the Presto driver with
+// OpenTelemetry.
+//
+// It creates a span for every query, with a child span for every HTTP
+// request sent for the query, and propagates the trace context to the server
+// in the request headers:
+//
+//	connector, err := presto.NewConnector(dsn, presto.WithTracer(otelpresto.NewTracer()))
+//	...
+//	db := sql.OpenDB(connector)
+package otelpresto
+
+import (
+	"context"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/timescale/presto-go-client/presto"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "github.com/timescale/presto-go-client/presto/otelpresto"
+
+// Attribute keys set on the spans.
+const (
+	QueryIDKey        = attribute.Key("presto.query_id")
+	StateKey          = attribute.Key("presto.state")
+	RowsKey           = attribute.Key("presto.rows")
+	ProcessedRowsKey  = attribute.Key("presto.processed_rows")
+	ProcessedBytesKey = attribute.Key("presto.processed_bytes")
+	RetriesKey        = attribute.Key("presto.retries")
+	CancelledKey      = attribute.Key("presto.cancelled")
+)
+
+// Option configures a tracer.
+type Option func(*tracer)
+
+// WithTracerProvider sets the provider of the tracer, instead of the global one.
+func WithTracerProvider(provider trace.TracerProvider) Option {
+	return func(t *tracer) {
+		t.provider = provider
+	}
+}
+
+// WithPropagator sets the propagator injecting the trace context in the
+// request headers, instead of the W3C trace context propagator.
+func WithPropagator(propagator propagation.TextMapPropagator) Option {
+	return func(t *tracer) {
+		t.propagator = propagator
+	}
+}
+
+// WithoutStatement leaves the query text out of the spans, e.g. when queries
+// may contain sensitive values.
+func WithoutStatement() Option {
+	return func(t *tracer) {
+		t.omitStatement = true
+	}
+}
+
+type tracer struct {
+	provider      trace.TracerProvider
+	propagator    propagation.TextMapPropagator
+	omitStatement bool
+	tracer        trace.Tracer
+}
+
+// NewTracer returns a tracer to pass to presto.WithTracer.
+func NewTracer(opts ...Option) presto.Tracer {
+	t := &tracer{
+		provider:   otel.GetTracerProvider(),
+		propagator: propagation.TraceContext{},
+	}
+	for _, opt := range opts {
+		opt(t)
+	}
+	t.tracer = t.provider.Tracer(instrumentationName)
+	return t
+}
+
+// StartQuery implements the presto.Tracer interface.
+func (t *tracer) StartQuery(ctx context.Context, query string) (context.Context, presto.QueryTrace) {
+	attrs := []attribute.KeyValue{attribute.String("db.system", "presto")}
+	if !t.omitStatement && query != "" {
+		attrs = append(attrs, attribute.String("db.statement", query))
+	}
+	ctx, span := t.tracer.Start(ctx, "presto.query",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attrs...),
+	)
+	return ctx, &queryTrace{tracer: t, span: span}
+}
+
+type queryTrace struct {
+	tracer  *tracer
+	span    trace.Span
+	queryID atomic.Value
+	retries int64
+}
+
+// StartRequest implements the presto.QueryTrace interface.
+func (q *queryTrace) StartRequest(ctx context.Context, req *http.Request) presto.RequestTrace {
+	// The cancellation of a query is sent outside the context of the query.
+	ctx = trace.ContextWithSpan(ctx, q.span)
+	ctx, span := q.tracer.tracer.Start(ctx, "presto.http "+req.Method,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("http.request.method", req.Method),
+			attribute.String("url.full", req.URL.Redacted()),
+		),
+	)
+	q.tracer.propagator.Inject(ctx, propagation.HeaderCarrier(req.Header))
+	return &requestTrace{query: q, span: span}
+}
+
+// Update implements the presto.QueryTrace interface.
+func (q *queryTrace) Update(info presto.QueryProgressInfo) {
+	if info.QueryId != "" && q.queryID.Load() == nil {
+		q.queryID.Store(info.QueryId)
+		q.span.SetAttributes(QueryIDKey.String(info.QueryId))
+	}
+	q.span.AddEvent("presto.progress", trace.WithAttributes(
+		StateKey.String(info.QueryStats.State),
+		ProcessedRowsKey.Int(info.QueryStats.ProcessedRows),
+		ProcessedBytesKey.Int(info.QueryStats.ProcessedBytes),
+	))
+}
+
+// Finish implements the presto.QueryTrace interface.
+func (q *queryTrace) Finish(done presto.QueryDone) {
+	if done.QueryId != "" && q.queryID.Load() == nil {
+		q.span.SetAttributes(QueryIDKey.String(done.QueryId))
+	}
+	q.span.SetAttributes(
+		StateKey.String(done.QueryStats.State),
+		RowsKey.Int64(done.Rows),
+		ProcessedRowsKey.Int(done.QueryStats.ProcessedRows),
+		ProcessedBytesKey.Int(done.QueryStats.ProcessedBytes),
+		RetriesKey.Int64(atomic.LoadInt64(&q.retries)),
+		CancelledKey.Bool(done.Cancelled),
+	)
+	if done.Err != nil {
+		q.span.RecordError(done.Err)
+		q.span.SetStatus(codes.Error, done.Err.Error())
+	}
+	q.span.End()
+}
+
+type requestTrace struct {
+	query   *queryTrace
+	span    trace.Span
+	retries int64
+}
+
+// Retry implements the presto.RequestTrace interface.
+func (r *requestTrace) Retry(statusCode int, delay time.Duration) {
+	r.retries++
+	atomic.AddInt64(&r.query.retries, 1)
+	r.span.AddEvent("presto.retry", trace.WithAttributes(
+		attribute.Int("http.response.status_code", statusCode),
+		attribute.String("presto.retry_delay", delay.String()),
+	))
+}
+
+// End implements the presto.RequestTrace interface.
+func (r *requestTrace) End(statusCode int, err error) {
+	if statusCode != 0 {
+		r.span.SetAttributes(attribute.Int("http.response.status_code", statusCode))
+	}
+	r.span.SetAttributes(RetriesKey.Int64(r.retries))
+	// The server answers the cancellation of a query with 204 No Content,
+	// which is reported as an error by the driver.
+	if err != nil && statusCode != http.StatusNoContent {
+		r.span.RecordError(err)
+		r.span.SetStatus(codes.Error, err.Error())
+	}
+	r.span.End()
+}
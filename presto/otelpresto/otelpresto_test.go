@@ -0,0 +1,150 @@
+// Copyright (c) Facebook, Inc. and its affiliates. All Rights Reserved
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package otelpresto
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/timescale/presto-go-client/presto"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// newServer returns a server running a query returning two rows in a single
+// page, which is busy the first time the page is polled. It records the
+// traceparent header of the requests it receives.
+func newServer(t *testing.T) (*httptest.Server, func() []string) {
+	var (
+		mu           sync.Mutex
+		traceparents []string
+		busy         = true
+	)
+	var s *httptest.Server
+	s = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		traceparents = append(traceparents, r.Header.Get("traceparent"))
+		retry := busy && r.URL.Path == "/v1/statement/q1/0"
+		if retry {
+			busy = false
+		}
+		mu.Unlock()
+		var resp map[string]interface{}
+		switch r.URL.Path {
+		case "/v1/statement":
+			resp = map[string]interface{}{
+				"id":      "q1",
+				"nextUri": s.URL + "/v1/statement/q1/0",
+				"stats":   map[string]interface{}{"state": "QUEUED"},
+			}
+		case "/v1/statement/q1/0":
+			if retry {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			resp = map[string]interface{}{
+				"id":      "q1",
+				"nextUri": s.URL + "/v1/statement/q1/1",
+				"columns": []map[string]interface{}{{"name": "x", "type": "bigint", "typeSignature": "bigint"}},
+				"data":    [][]interface{}{{1}, {2}},
+				"stats":   map[string]interface{}{"state": "RUNNING"},
+			}
+		case "/v1/statement/q1/1":
+			resp = map[string]interface{}{
+				"id":    "q1",
+				"stats": map[string]interface{}{"state": "FINISHED", "processedRows": 2, "processedBytes": 16},
+			}
+		default:
+			http.NotFound(w, r)
+			return
+		}
+		json.NewEncoder(w).Encode(resp)
+	}))
+	t.Cleanup(s.Close)
+	return s, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), traceparents...)
+	}
+}
+
+func attributes(span sdktrace.ReadOnlySpan) map[attribute.Key]attribute.Value {
+	attrs := make(map[attribute.Key]attribute.Value)
+	for _, kv := range span.Attributes() {
+		attrs[kv.Key] = kv.Value
+	}
+	return attrs
+}
+
+func TestTracer(t *testing.T) {
+	s, traceparents := newServer(t)
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	connector, err := presto.NewConnector("http://user@"+s.Listener.Addr().String(), presto.WithTracer(NewTracer(WithTracerProvider(provider))))
+	require.NoError(t, err)
+	db := sql.OpenDB(connector)
+	defer db.Close()
+
+	rows, err := db.QueryContext(context.Background(), "SELECT x FROM t")
+	require.NoError(t, err)
+	n := 0
+	for rows.Next() {
+		n++
+	}
+	require.NoError(t, rows.Err())
+	require.NoError(t, rows.Close())
+	assert.Equal(t, 2, n)
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 4)
+	query := spans[len(spans)-1]
+	assert.Equal(t, "presto.query", query.Name())
+	assert.Equal(t, trace.SpanKindClient, query.SpanKind())
+	attrs := attributes(query)
+	assert.Equal(t, "SELECT x FROM t", attrs["db.statement"].AsString())
+	assert.Equal(t, "q1", attrs[QueryIDKey].AsString())
+	assert.Equal(t, "FINISHED", attrs[StateKey].AsString())
+	assert.Equal(t, int64(2), attrs[RowsKey].AsInt64())
+	assert.Equal(t, int64(2), attrs[ProcessedRowsKey].AsInt64())
+	assert.Equal(t, int64(16), attrs[ProcessedBytesKey].AsInt64())
+	assert.Equal(t, int64(1), attrs[RetriesKey].AsInt64())
+
+	var names []string
+	var retries []int64
+	for _, span := range spans[:len(spans)-1] {
+		assert.Equal(t, query.SpanContext().SpanID(), span.Parent().SpanID())
+		assert.Equal(t, query.SpanContext().TraceID(), span.SpanContext().TraceID())
+		names = append(names, span.Name())
+		retries = append(retries, attributes(span)[RetriesKey].AsInt64())
+	}
+	assert.Equal(t, []string{"presto.http POST", "presto.http GET", "presto.http GET"}, names)
+	assert.Equal(t, []int64{0, 1, 0}, retries)
+
+	// Each request carries the context of its span, the retried request
+	// is sent twice.
+	traceparent := func(span sdktrace.ReadOnlySpan) string {
+		return "00-" + span.SpanContext().TraceID().String() + "-" + span.SpanContext().SpanID().String() + "-01"
+	}
+	assert.Equal(t, []string{traceparent(spans[0]), traceparent(spans[1]), traceparent(spans[1]), traceparent(spans[2])}, traceparents())
+}
@@ -61,6 +61,7 @@ import (
 	"fmt"
 	"io"
 	"io/ioutil"
+	"log/slog"
 	"math"
 	"net/http"
 	"net/url"
@@ -69,8 +70,10 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/timescale/presto-go-client/presto/types"
 	"gopkg.in/jcmturner/gokrb5.v6/client"
 	"gopkg.in/jcmturner/gokrb5.v6/config"
 	"gopkg.in/jcmturner/gokrb5.v6/keytab"
@@ -81,10 +84,15 @@ func init() {
 }
 
 var (
-	// DefaultQueryTimeout is the default timeout for queries executed without a context.
+	// DefaultQueryTimeout is the default timeout of each request sent without
+	// a context deadline, unless the DSN sets submit_timeout, poll_timeout or
+	// query_timeout. Queries polled for longer than that without a context
+	// deadline must set one of them. Connections read it when they are
+	// opened.
 	DefaultQueryTimeout = 60 * time.Second
 
-	// DefaultCancelQueryTimeout is the timeout for the request to cancel queries in Presto.
+	// DefaultCancelQueryTimeout is the timeout for the request to cancel queries in Presto,
+	// unless the DSN sets cancel_timeout. Connections read it when they are opened.
 	DefaultCancelQueryTimeout = 30 * time.Second
 
 	// ErrOperationNotSupported indicates that a database operation is not supported.
@@ -96,6 +104,12 @@ var (
 	// ErrUnsupportedHeader indicates that the server response contains an unsupported header.
 	ErrUnsupportedHeader = errors.New("presto: server response contains an unsupported header")
 
+	// ErrMixedParams indicates that a query got both named and positional arguments.
+	ErrMixedParams = errors.New("presto: named and positional arguments cannot be mixed")
+
+	// ErrPartialCancelUnavailable indicates that a query has no running stage to cancel.
+	ErrPartialCancelUnavailable = errors.New("presto: partial cancel not available")
+
 	// ErrInvalidProgressCallbackHeader indicates that server did not get valid headers for progress callback
 	ErrInvalidProgressCallbackHeader = errors.New("presto: both " + prestoProgressCallbackParam + " and " + prestoProgressCallbackPeriodParam + " must be set when using progress callback")
 )
@@ -121,6 +135,10 @@ const (
 
 	prestoProgressCallbackParam       = prestoHeaderPrefix + `Progress-Callback`
 	prestoProgressCallbackPeriodParam = prestoHeaderPrefix + `Progress-Callback-Period`
+	prestoWarningCallbackParam        = prestoHeaderPrefix + `Warning-Callback`
+	prestoResumeParam                 = prestoHeaderPrefix + `Resume-URI`
+	prestoPartialCancelParam          = prestoHeaderPrefix + `Partial-Cancel`
+	prestoQueryPriorityParam          = prestoHeaderPrefix + `Query-Priority`
 
 	prestoAddedPrepareHeader       = prestoHeaderPrefix + `Added-Prepare`
 	prestoDeallocatedPrepareHeader = prestoHeaderPrefix + `Deallocated-Prepare`
@@ -155,25 +173,35 @@ var _ driver.Driver = &Driver{}
 
 // Config is a configuration that can be encoded to a DSN string.
 type Config struct {
-	ServerURI          string            // URI of the Presto server, e.g. http://user@localhost:8080
-	Source             string            // Source of the connection (optional)
-	Catalog            string            // Catalog (optional)
-	Schema             string            // Schema (optional)
-	SessionProperties  map[string]string // Session properties (optional)
-	ExtraCredentials   map[string]string // Extra credentials (optional)
-	CustomClientName   string            // Custom client name (optional)
-	KerberosEnabled    string            // KerberosEnabled (optional, default is false)
-	KerberosKeytabPath string            // Kerberos Keytab Path (optional)
-	KerberosPrincipal  string            // Kerberos Principal used to authenticate to KDC (optional)
-	KerberosRealm      string            // The Kerberos Realm (optional)
-	KerberosConfigPath string            // The krb5 config path (optional)
-	SSLCertPath        string            // The SSL cert path for TLS verification (optional)
-	SSLCert            string            // The SSL cert for TLS verification (optional)
+	ServerURI            string            // URI of the Presto server, e.g. http://user@localhost:8080
+	Source               string            // Source of the connection (optional)
+	Catalog              string            // Catalog (optional)
+	Schema               string            // Schema (optional)
+	SessionProperties    map[string]string // Session properties (optional)
+	ExtraCredentials     map[string]string // Extra credentials (optional)
+	CustomClientName     string            // Custom client name (optional)
+	KerberosEnabled      string            // KerberosEnabled (optional, default is false)
+	KerberosKeytabPath   string            // Kerberos Keytab Path (optional)
+	KerberosPrincipal    string            // Kerberos Principal used to authenticate to KDC (optional)
+	KerberosRealm        string            // The Kerberos Realm (optional)
+	KerberosConfigPath   string            // The krb5 config path (optional)
+	SSLCertPath          string            // The SSL cert path for TLS verification (optional)
+	SSLCert              string            // The SSL cert for TLS verification (optional)
+	KeepSessionState     bool              // Keep session state changes when the connection is returned to the pool (optional, default is false)
+	InterpolateParams    bool              // Replace query placeholders with the query arguments on the client (optional, default is false)
+	LoadBalancing        string            // Strategy to pick the coordinator of each query when ServerURI lists several hosts, e.g. http://user@c1:8080,c2:8080 (optional, default is failover)
+	HealthCheckInterval  time.Duration     // Time a coordinator that could not be reached is left out (optional, default is DefaultHealthCheckInterval)
+	MaxConcurrentQueries int               // Maximum number of queries run at once by the connections of a Connector or sql.DB (optional, default is no limit)
+	SubmitTimeout        time.Duration     // Timeout of the request submitting a statement (optional, default is the context deadline, QueryTimeout or DefaultQueryTimeout)
+	PollTimeout          time.Duration     // Timeout of each request polling the results of a query (optional, default is the context deadline, QueryTimeout or DefaultQueryTimeout)
+	QueryTimeout         time.Duration     // Total time a query may run, also set as the query_max_run_time session property (optional, default is no limit)
+	IdleTimeout          time.Duration     // Time the rows of a query may be left unread before the query is cancelled (optional, default is no limit)
+	CancelTimeout        time.Duration     // Timeout of the request cancelling a query (optional, default is DefaultCancelQueryTimeout)
 }
 
 // FormatDSN returns a DSN string from the configuration.
 func (c *Config) FormatDSN() (string, error) {
-	serverURL, err := url.Parse(c.ServerURI)
+	serverURL, hosts, err := parseDSN(c.ServerURI)
 	if err != nil {
 		return "", err
 	}
@@ -250,36 +278,97 @@ func (c *Config) FormatDSN() (string, error) {
 			query[k] = []string{v}
 		}
 	}
+	if c.KeepSessionState {
+		query.Add("keep_session_state", "true")
+	}
+	if c.InterpolateParams {
+		query.Add("interpolate_params", "true")
+	}
+	if c.LoadBalancing != "" {
+		query.Add("load_balancing", c.LoadBalancing)
+	}
+	if c.HealthCheckInterval != 0 {
+		query.Add("health_check_interval", c.HealthCheckInterval.String())
+	}
+	if c.MaxConcurrentQueries != 0 {
+		query.Add("max_concurrent_queries", strconv.Itoa(c.MaxConcurrentQueries))
+	}
+	for k, v := range map[string]time.Duration{
+		"submit_timeout": c.SubmitTimeout,
+		"poll_timeout":   c.PollTimeout,
+		"query_timeout":  c.QueryTimeout,
+		"idle_timeout":   c.IdleTimeout,
+		"cancel_timeout": c.CancelTimeout,
+	} {
+		if v != 0 {
+			query.Add(k, v.String())
+		}
+	}
+	serverURL.Host = strings.Join(hosts, ",")
 	serverURL.RawQuery = query.Encode()
 	return serverURL.String(), nil
 }
 
 // Conn is a Presto connection.
+//
+// Session state changes requested by the server, such as the ones caused by
+// USE, SET SESSION or PREPARE statements, are tracked by the connection.
+// Unless keep_session_state is set in the DSN, they are discarded when the
+// connection is returned to the pool, so that every user of the pool starts
+// from the session state described by the DSN.
 type Conn struct {
-	baseURL               string
+	coordinators          *coordinatorPool
 	auth                  *url.Userinfo
 	httpClient            http.Client
-	httpHeaders           http.Header
+	session               *sessionState
+	dsnHeaders            http.Header
+	keepSessionState      bool
+	interpolateParams     bool
+	closed                atomic.Bool
 	kerberosClient        client.Client
 	kerberosEnabled       bool
 	progressUpdater       ProgressUpdater
 	progressUpdaterPeriod queryProgressCallbackPeriod
+	tracer                Tracer
+	metrics               Metrics
+	logger                *slog.Logger
+	interceptors          []Interceptor
+	auditSink             AuditSink
+	auditRedact           bool
+	limiter               *queryLimiter
+	timeouts              timeouts
 }
 
 var (
 	_ driver.Conn               = &Conn{}
 	_ driver.ConnPrepareContext = &Conn{}
+	_ driver.SessionResetter    = &Conn{}
+	_ driver.Validator          = &Conn{}
 )
 
 func newConn(dsn string) (*Conn, error) {
-	serverURL, err := url.Parse(dsn)
+	serverURL, hosts, err := parseDSN(dsn)
 	if err != nil {
 		return nil, fmt.Errorf("presto: malformed dsn: %w", err)
 	}
+	coordinators, err := newCoordinatorPool(serverURL, hosts)
+	if err != nil {
+		return nil, err
+	}
 
 	query := serverURL.Query()
 
 	kerberosEnabled, _ := strconv.ParseBool(query.Get(KerberosEnabledConfig))
+	keepSessionState, _ := strconv.ParseBool(query.Get("keep_session_state"))
+	interpolateParams, _ := strconv.ParseBool(query.Get("interpolate_params"))
+	limiter, err := newQueryLimiter(query.Get("max_concurrent_queries"))
+	if err != nil {
+		return nil, err
+	}
+	timeouts, err := parseTimeouts(query)
+	if err != nil {
+		return nil, err
+	}
 
 	var kerberosClient client.Client
 
@@ -335,11 +424,14 @@ func newConn(dsn string) (*Conn, error) {
 	}
 
 	c := &Conn{
-		baseURL:         serverURL.Scheme + "://" + serverURL.Host,
-		httpClient:      *httpClient,
-		httpHeaders:     make(http.Header),
-		kerberosClient:  kerberosClient,
-		kerberosEnabled: kerberosEnabled,
+		coordinators:      coordinators,
+		limiter:           limiter,
+		timeouts:          timeouts,
+		httpClient:        *httpClient,
+		keepSessionState:  keepSessionState,
+		interpolateParams: interpolateParams,
+		kerberosClient:    kerberosClient,
+		kerberosEnabled:   kerberosEnabled,
 	}
 
 	var user string
@@ -351,18 +443,20 @@ func newConn(dsn string) (*Conn, error) {
 		}
 	}
 
+	c.dsnHeaders = make(http.Header)
 	for k, v := range map[string]string{
 		prestoUserHeader:            user,
 		prestoSourceHeader:          query.Get("source"),
 		prestoCatalogHeader:         query.Get("catalog"),
 		prestoSchemaHeader:          query.Get("schema"),
-		prestoSessionHeader:         query.Get("session_properties"),
+		prestoSessionHeader:         timeouts.sessionProperties(query.Get("session_properties")),
 		prestoExtraCredentialHeader: query.Get("extra_credentials"),
 	} {
 		if v != "" {
-			c.httpHeaders.Add(k, v)
+			c.dsnHeaders.Add(k, v)
 		}
 	}
+	c.session = newSessionState(c.dsnHeaders)
 
 	return c, nil
 }
@@ -440,10 +534,39 @@ func (c *Conn) PrepareContext(ctx context.Context, query string) (driver.Stmt, e
 }
 
 // Close implements the driver.Conn interface.
+//
+// Prepared statements only exist in the session headers of the connection,
+// so they are dropped locally, without any request to the server.
 func (c *Conn) Close() error {
+	if !c.closed.CompareAndSwap(false, true) {
+		return nil
+	}
+	c.session.dropPreparedStatements()
+	return nil
+}
+
+// ResetSession implements the driver.SessionResetter interface.
+//
+// It restores the session state described by the DSN, unless
+// keep_session_state is enabled. Progress callbacks registered by previous
+// queries are always discarded.
+func (c *Conn) ResetSession(ctx context.Context) error {
+	c.progressUpdater = nil
+	c.progressUpdaterPeriod = queryProgressCallbackPeriod{}
+	if !c.keepSessionState {
+		c.session.reset(c.dsnHeaders)
+	}
 	return nil
 }
 
+// IsValid implements the driver.Validator interface.
+//
+// A connection is not valid once it was closed, or after the server sent a
+// session state change the driver is unable to track.
+func (c *Conn) IsValid() bool {
+	return !c.closed.Load() && !c.session.isInvalid()
+}
+
 func (c *Conn) newRequest(method, url string, body io.Reader, hs http.Header) (*http.Request, error) {
 	req, err := http.NewRequest(method, url, body)
 	if err != nil {
@@ -453,11 +576,12 @@ func (c *Conn) newRequest(method, url string, body io.Reader, hs http.Header) (*
 	if c.kerberosEnabled {
 		err = c.kerberosClient.SetSPNEGOHeader(req, "presto/"+req.URL.Hostname())
 		if err != nil {
+			c.debug(context.Background(), "presto: kerberos authentication failed", "host", req.URL.Hostname(), "error", err)
 			return nil, fmt.Errorf("error setting client SPNEGO header: %w", err)
 		}
 	}
 
-	for k, v := range c.httpHeaders {
+	for k, v := range c.session.snapshot() {
 		req.Header[k] = v
 	}
 	for k, v := range hs {
@@ -471,7 +595,19 @@ func (c *Conn) newRequest(method, url string, body io.Reader, hs http.Header) (*
 	return req, nil
 }
 
-func (c *Conn) roundTrip(ctx context.Context, req *http.Request) (*http.Response, error) {
+func (c *Conn) roundTrip(ctx context.Context, req *http.Request) (resp *http.Response, err error) {
+	trace := startRequest(ctx, req)
+	if trace != nil {
+		defer func() {
+			var statusCode int
+			if resp != nil {
+				statusCode = resp.StatusCode
+			} else if qferr, ok := err.(*ErrQueryFailed); ok {
+				statusCode = qferr.StatusCode
+			}
+			trace.End(statusCode, err)
+		}()
+	}
 	delay := 100 * time.Millisecond
 	const maxDelayBetweenRequests = float64(15 * time.Second)
 	timer := time.NewTimer(0)
@@ -481,56 +617,34 @@ func (c *Conn) roundTrip(ctx context.Context, req *http.Request) (*http.Response
 		case <-ctx.Done():
 			return nil, ctx.Err()
 		case <-timer.C:
-			timeout := DefaultQueryTimeout
-			if deadline, ok := ctx.Deadline(); ok {
-				timeout = time.Until(deadline)
-			}
 			client := c.httpClient
-			client.Timeout = timeout
+			client.Timeout = c.requestTimeout(ctx)
 			req.Cancel = ctx.Done()
+			c.debug(ctx, "presto: sending request", "method", req.Method, "url", req.URL.Redacted(), "headers", logHeaders(req.Header))
+			start := time.Now()
 			resp, err := client.Do(req)
 			if err != nil {
+				c.debug(ctx, "presto: request failed", "method", req.Method, "url", req.URL.Redacted(), "error", err)
 				return nil, &ErrQueryFailed{Reason: err}
 			}
+			c.debug(ctx, "presto: received response", "method", req.Method, "url", req.URL.Redacted(), "status", resp.StatusCode, "duration", time.Since(start))
 			switch resp.StatusCode {
 			case http.StatusOK:
-				for src, dst := range responseToRequestHeaderMap {
-					if v := resp.Header.Get(src); v != "" {
-						c.httpHeaders.Set(dst, v)
-					}
-				}
-				if v := resp.Header.Get(prestoAddedPrepareHeader); v != "" {
-					c.httpHeaders.Add(preparedStatementHeader, v)
-				}
-				if v := resp.Header.Get(prestoDeallocatedPrepareHeader); v != "" {
-					values := c.httpHeaders.Values(preparedStatementHeader)
-					c.httpHeaders.Del(preparedStatementHeader)
-					for _, v2 := range values {
-						if !strings.HasPrefix(v2, v+"=") {
-							c.httpHeaders.Add(preparedStatementHeader, v2)
-						}
-					}
-				}
-				if v := resp.Header.Get(prestoSetSessionHeader); v != "" {
-					c.httpHeaders.Add(prestoSessionHeader, v)
-				}
-				if v := resp.Header.Get(prestoClearSessionHeader); v != "" {
-					values := c.httpHeaders.Values(prestoSessionHeader)
-					c.httpHeaders.Del(prestoSessionHeader)
-					for _, v2 := range values {
-						if !strings.HasPrefix(v2, v+"=") {
-							c.httpHeaders.Add(prestoSessionHeader, v2)
-						}
-					}
-				}
-				for _, name := range unsupportedResponseHeaders {
-					if v := resp.Header.Get(name); v != "" {
-						return nil, ErrUnsupportedHeader
-					}
+				c.logSessionChanges(ctx, resp.Header)
+				if err := c.session.update(resp.Header); err != nil {
+					resp.Body.Close()
+					return nil, err
 				}
 				return resp, nil
 			case http.StatusServiceUnavailable:
 				resp.Body.Close()
+				if trace != nil {
+					trace.Retry(resp.StatusCode, delay)
+				}
+				if c.metrics != nil {
+					c.metrics.RequestRetried(resp.StatusCode)
+				}
+				c.debug(ctx, "presto: retrying request", "method", req.Method, "url", req.URL.Redacted(), "status", resp.StatusCode, "delay", delay)
 				timer.Reset(delay)
 				delay = time.Duration(math.Min(
 					float64(delay)*math.Phi,
@@ -588,6 +702,13 @@ type driverStmt struct {
 	statsCh        chan QueryProgressInfo
 	errors         chan error
 	doneCh         chan struct{}
+	warningHandler WarningHandler
+	warnings       map[stmtWarning]bool
+	resumeURI      string
+	partialCancel  *PartialCanceler
+	run            *queryRun
+	baseURL        string // Coordinator running the query
+	unlimited      bool   // Runs without waiting for a slot of the limiter
 }
 
 var (
@@ -631,18 +752,21 @@ func (st *driverStmt) Exec(args []driver.Value) (driver.Result, error) {
 }
 
 func (st *driverStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	ctx = st.startQuery(ctx)
 	sr, err := st.exec(ctx, args)
 	if err != nil {
 		return nil, err
 	}
 	rows := &driverRows{
-		ctx:          ctx,
-		stmt:         st,
-		queryID:      sr.ID,
-		nextURI:      sr.NextURI,
-		rowsAffected: sr.UpdateCount,
-		statsCh:      st.statsCh,
-		doneCh:       st.doneCh,
+		ctx:         ctx,
+		stmt:        st,
+		queryID:     sr.ID,
+		nextURI:     sr.NextURI,
+		updateType:  sr.UpdateType,
+		updateCount: sr.UpdateCount,
+		stats:       sr.Stats,
+		statsCh:     st.statsCh,
+		doneCh:      st.doneCh,
 	}
 	// consume all results, if there are any
 	for err == nil {
@@ -652,7 +776,12 @@ func (st *driverStmt) ExecContext(ctx context.Context, args []driver.NamedValue)
 	if err != nil && err != io.EOF {
 		return nil, err
 	}
-	return rows, nil
+	return &Result{
+		QueryId:     rows.queryID,
+		UpdateType:  rows.updateType,
+		UpdateCount: rows.updateCount,
+		QueryStats:  rows.stats,
+	}, nil
 }
 
 func (st *driverStmt) CheckNamedValue(arg *driver.NamedValue) error {
@@ -673,6 +802,18 @@ func (st *driverStmt) CheckNamedValue(arg *driver.NamedValue) error {
 			if arg.Name == prestoProgressCallbackPeriodParam {
 				return nil
 			}
+			if arg.Name == prestoWarningCallbackParam {
+				return nil
+			}
+			if arg.Name == prestoResumeParam {
+				return nil
+			}
+			if arg.Name == prestoPartialCancelParam {
+				return nil
+			}
+			if arg.Name == prestoQueryPriorityParam {
+				return nil
+			}
 		}
 	}
 
@@ -680,13 +821,15 @@ func (st *driverStmt) CheckNamedValue(arg *driver.NamedValue) error {
 }
 
 type stmtResponse struct {
-	ID          string    `json:"id"`
-	InfoURI     string    `json:"infoUri"`
-	NextURI     string    `json:"nextUri"`
-	Stats       stmtStats `json:"stats"`
-	Error       stmtError `json:"error"`
-	UpdateType  string    `json:"updateType"`
-	UpdateCount int64     `json:"updateCount"`
+	ID               string        `json:"id"`
+	InfoURI          string        `json:"infoUri"`
+	PartialCancelURI string        `json:"partialCancelUri"`
+	NextURI          string        `json:"nextUri"`
+	Stats            stmtStats     `json:"stats"`
+	Error            stmtError     `json:"error"`
+	Warnings         []stmtWarning `json:"warnings"`
+	UpdateType       string        `json:"updateType"`
+	UpdateCount      int64         `json:"updateCount"`
 }
 
 type stmtStats struct {
@@ -715,6 +858,16 @@ type stmtError struct {
 	// Other fields omitted
 }
 
+type stmtWarning struct {
+	WarningCode stmtWarningCode `json:"warningCode"`
+	Message     string          `json:"message"`
+}
+
+type stmtWarningCode struct {
+	Code int    `json:"code"`
+	Name string `json:"name"`
+}
+
 type stmtErrorLocation struct {
 	LineNumber   int `json:"lineNumber"`
 	ColumnNumber int `json:"columnNumber"`
@@ -751,6 +904,7 @@ func (st *driverStmt) Query(args []driver.Value) (driver.Rows, error) {
 }
 
 func (st *driverStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	ctx = st.startQuery(ctx)
 	sr, err := st.exec(ctx, args)
 	if err != nil {
 		return nil, err
@@ -766,17 +920,47 @@ func (st *driverStmt) QueryContext(ctx context.Context, args []driver.NamedValue
 	if err = rows.fetch(); err != nil && err != io.EOF {
 		return nil, err
 	}
+	rows.startIdleTimer()
 	return rows, nil
 }
 
 func (st *driverStmt) exec(ctx context.Context, args []driver.NamedValue) (*stmtResponse, error) {
+	sr, err := st.submit(ctx, args)
+	if err != nil {
+		done := QueryDone{Err: err}
+		if sr != nil {
+			done.QueryId = sr.ID
+			done.QueryStats = sr.Stats
+		}
+		st.run.finish(done)
+	}
+	if sr == nil {
+		return nil, err
+	}
+	st.start(ctx, sr)
+	return sr, err
+}
+
+// submit sends the statement to the server and returns its first response,
+// or a response pointing to the resume URI if the statement resumes polling
+// a query submitted earlier.
+func (st *driverStmt) submit(ctx context.Context, args []driver.NamedValue) (*stmtResponse, error) {
+	// A prepared statement may run several times, the options passed to
+	// one execution don't carry over to the next.
+	st.warningHandler = nil
+	st.warnings = nil
+	st.resumeURI = ""
+	st.partialCancel = nil
+
 	query := st.query
 	hs := make(http.Header)
 	// Ensure the server returns timestamps preserving their precision, without truncating them to timestamp(3).
 	hs.Add("X-Presto-Client-Capabilities", "PARAMETRIC_DATETIME")
 
+	var ss []string
+	var named []namedParam
+	priority := PriorityNormal
 	if len(args) > 0 {
-		var ss []string
 		for _, arg := range args {
 			if arg.Name == prestoProgressCallbackParam {
 				st.conn.progressUpdater = arg.Value.(ProgressUpdater)
@@ -786,6 +970,38 @@ func (st *driverStmt) exec(ctx context.Context, args []driver.NamedValue) (*stmt
 				st.conn.progressUpdaterPeriod.Period = arg.Value.(time.Duration)
 				continue
 			}
+			if arg.Name == prestoWarningCallbackParam {
+				handler, ok := arg.Value.(WarningHandler)
+				if !ok {
+					return nil, fmt.Errorf("presto: %s must be a WarningHandler, got %T", prestoWarningCallbackParam, arg.Value)
+				}
+				st.warningHandler = handler
+				continue
+			}
+			if arg.Name == prestoResumeParam {
+				nextURI, ok := arg.Value.(string)
+				if !ok {
+					return nil, fmt.Errorf("presto: %s must be a string, got %T", prestoResumeParam, arg.Value)
+				}
+				st.resumeURI = nextURI
+				continue
+			}
+			if arg.Name == prestoPartialCancelParam {
+				pc, ok := arg.Value.(*PartialCanceler)
+				if !ok {
+					return nil, fmt.Errorf("presto: %s must be a *PartialCanceler, got %T", prestoPartialCancelParam, arg.Value)
+				}
+				st.partialCancel = pc
+				continue
+			}
+			if arg.Name == prestoQueryPriorityParam {
+				p, ok := arg.Value.(QueryPriority)
+				if !ok {
+					return nil, fmt.Errorf("presto: %s must be a QueryPriority, got %T", prestoQueryPriorityParam, arg.Value)
+				}
+				priority = p
+				continue
+			}
 
 			s, err := Serial(arg.Value)
 			if err != nil {
@@ -800,87 +1016,160 @@ func (st *driverStmt) exec(ctx context.Context, args []driver.NamedValue) (*stmt
 				}
 
 				hs.Add(arg.Name, headerValue)
+			} else if arg.Name != "" {
+				named = append(named, namedParam{name: arg.Name, value: s})
 			} else {
-				if hs.Get(preparedStatementHeader) == "" {
-					for _, v := range st.conn.httpHeaders.Values(preparedStatementHeader) {
-						hs.Add(preparedStatementHeader, v)
-					}
-					hs.Add(preparedStatementHeader, preparedStatementName+"="+url.QueryEscape(st.query))
-				}
 				ss = append(ss, s)
 			}
 		}
 		if (st.conn.progressUpdater != nil && st.conn.progressUpdaterPeriod.Period == 0) || (st.conn.progressUpdater == nil && st.conn.progressUpdaterPeriod.Period > 0) {
 			return nil, ErrInvalidProgressCallbackHeader
 		}
+	}
+	if st.resumeURI == "" {
+		intercepted, err := st.conn.beforeStatement(ctx, query, hs)
+		if err != nil {
+			st.run.startAudit(query, hs)
+			return nil, err
+		}
+		query = intercepted
+		st.run.startAudit(query, hs)
+	} else {
+		st.run.startResumeAudit(st.resumeURI, hs)
+	}
+	if len(named) > 0 {
 		if len(ss) > 0 {
-			query = "EXECUTE " + preparedStatementName + " USING " + strings.Join(ss, ", ")
+			return nil, ErrMixedParams
+		}
+		var err error
+		if query, ss, err = bindNamedParams(query, named); err != nil {
+			return nil, err
 		}
 	}
+	if len(ss) > 0 && st.conn.interpolateParams {
+		var err error
+		if query, err = interpolateParams(query, ss); err != nil {
+			return nil, err
+		}
+	} else if len(ss) > 0 {
+		for _, v := range st.conn.session.values(preparedStatementHeader) {
+			hs.Add(preparedStatementHeader, v)
+		}
+		hs.Add(preparedStatementHeader, preparedStatementName+"="+url.QueryEscape(query))
+		query = "EXECUTE " + preparedStatementName + " USING " + strings.Join(ss, ", ")
+	}
 
-	req, err := st.conn.newRequest("POST", st.conn.baseURL+"/v1/statement", strings.NewReader(query), hs)
-	if err != nil {
-		return nil, err
+	st.partialCancel.bind(st.conn, st.user)
+
+	if !st.unlimited {
+		if err := st.run.acquire(ctx, priority); err != nil {
+			return nil, err
+		}
 	}
 
-	resp, err := st.conn.roundTrip(ctx, req)
-	if err != nil {
-		return nil, err
+	if st.resumeURI != "" {
+		baseURL, err := st.conn.checkResumeURI(st.resumeURI)
+		if err != nil {
+			return nil, err
+		}
+		st.baseURL = baseURL
+		return &stmtResponse{NextURI: st.resumeURI}, nil
+	}
+
+	// The next URIs returned by the coordinator accepting the query point
+	// to itself, so the query sticks to it.
+	var resp *http.Response
+	candidates := st.conn.coordinators.candidates(st.conn)
+	for i, baseURL := range candidates {
+		req, err := st.conn.newRequest("POST", baseURL+"/v1/statement", strings.NewReader(query), hs)
+		if err != nil {
+			return nil, err
+		}
+		resp, err = st.conn.roundTrip(withRequestTimeout(ctx, st.conn.timeouts.submit), req)
+		if err == nil {
+			st.baseURL = baseURL
+			break
+		}
+		if len(candidates) == 1 || !isUnreachable(ctx, err) {
+			return nil, err
+		}
+		st.conn.coordinators.markDown(baseURL)
+		if i == len(candidates)-1 {
+			return nil, err
+		}
+		st.conn.debug(ctx, "presto: coordinator unavailable, trying the next one", "url", baseURL, "error", err)
 	}
 
 	defer resp.Body.Close()
 	var sr stmtResponse
 	d := json.NewDecoder(resp.Body)
 	d.UseNumber()
-	err = d.Decode(&sr)
+	err := d.Decode(&sr)
 	if err != nil {
 		return nil, fmt.Errorf("presto: %w", err)
 	}
+	st.reportWarnings(sr.ID, sr.Warnings)
+	st.partialCancel.setURI(sr.PartialCancelURI)
+	st.run.update(sr.ID, sr.Stats)
+	err = handleResponseError(resp.StatusCode, sr.Error)
+	st.conn.afterPage(ctx, &Page{QueryId: sr.ID, QueryStats: sr.Stats, Err: err})
+	return &sr, err
+}
 
+// start launches the goroutines polling the next URIs of the query.
+func (st *driverStmt) start(ctx context.Context, sr *stmtResponse) {
 	st.doneCh = make(chan struct{})
 	st.nextURIs = make(chan string)
 	st.httpResponses = make(chan *http.Response)
 	st.queryResponses = make(chan queryResponse)
 	st.errors = make(chan error)
+	// The goroutines keep their own channels, the statement gets new ones
+	// when it runs again.
+	doneCh, nextURIs, httpResponses, queryResponses, errs := st.doneCh, st.nextURIs, st.httpResponses, st.queryResponses, st.errors
+	pollCtx := withRequestTimeout(ctx, st.conn.timeouts.poll)
 	go func() {
-		defer close(st.httpResponses)
+		defer close(httpResponses)
 		for {
 			select {
-			case nextURI := <-st.nextURIs:
+			case nextURI := <-nextURIs:
 				if nextURI == "" {
 					return
 				}
 				hs := make(http.Header)
-				hs.Add(prestoUserHeader, st.user)
+				if st.user != "" {
+					hs.Add(prestoUserHeader, st.user)
+				}
 				req, err := st.conn.newRequest("GET", nextURI, nil, hs)
 				if err != nil {
-					st.errors <- err
+					errs <- err
 					return
 				}
-				resp, err := st.conn.roundTrip(ctx, req)
+				start := time.Now()
+				resp, err := st.conn.roundTrip(pollCtx, req)
 				if err != nil {
 					if ctx.Err() == context.Canceled {
-						st.errors <- context.Canceled
+						errs <- context.Canceled
 						return
 					}
-					st.errors <- err
+					errs <- err
 					return
 				}
+				st.conn.meterBody(resp, time.Since(start))
 				select {
-				case st.httpResponses <- resp:
-				case <-st.doneCh:
+				case httpResponses <- resp:
+				case <-doneCh:
 					return
 				}
-			case <-st.doneCh:
+			case <-doneCh:
 				return
 			}
 		}
 	}()
 	go func() {
-		defer close(st.queryResponses)
+		defer close(queryResponses)
 		for {
 			select {
-			case resp := <-st.httpResponses:
+			case resp := <-httpResponses:
 				if resp == nil {
 					return
 				}
@@ -888,32 +1177,34 @@ func (st *driverStmt) exec(ctx context.Context, args []driver.NamedValue) (*stmt
 				var qresp queryResponse
 				d := json.NewDecoder(resp.Body)
 				d.UseNumber()
-				err = d.Decode(&qresp)
+				err := d.Decode(&qresp)
 				if err != nil {
-					st.errors <- fmt.Errorf("presto: %w", err)
+					errs <- fmt.Errorf("presto: %w", err)
 					return
 				}
 				err = resp.Body.Close()
 				if err != nil {
-					st.errors <- err
+					errs <- err
 					return
 				}
+				st.conn.reportPage(resp)
 				err = handleResponseError(resp.StatusCode, qresp.Error)
+				st.conn.afterPage(ctx, &Page{QueryId: qresp.ID, Rows: len(qresp.Data), QueryStats: qresp.Stats, Err: err})
 				if err != nil {
-					st.errors <- err
+					errs <- err
 					return
 				}
 				select {
-				case st.nextURIs <- qresp.NextURI:
-				case <-st.doneCh:
+				case nextURIs <- qresp.NextURI:
+				case <-doneCh:
 					return
 				}
 				select {
-				case st.queryResponses <- qresp:
-				case <-st.doneCh:
+				case queryResponses <- qresp:
+				case <-doneCh:
 					return
 				}
-			case <-st.doneCh:
+			case <-doneCh:
 				return
 			}
 		}
@@ -921,15 +1212,17 @@ func (st *driverStmt) exec(ctx context.Context, args []driver.NamedValue) (*stmt
 	st.nextURIs <- sr.NextURI
 	if st.conn.progressUpdater != nil {
 		st.statsCh = make(chan QueryProgressInfo)
+		statsCh := st.statsCh
+		progressUpdater := st.conn.progressUpdater
 
 		// progress updater go func
 		go func() {
 			for {
 				select {
-				case stats := <-st.statsCh:
-					st.conn.progressUpdater.Update(stats)
-				case <-st.doneCh:
-					close(st.statsCh)
+				case stats := <-statsCh:
+					progressUpdater.Update(stats)
+				case <-doneCh:
+					close(statsCh)
 					return
 				}
 			}
@@ -948,7 +1241,6 @@ func (st *driverStmt) exec(ctx context.Context, args []driver.NamedValue) (*stmt
 		st.conn.progressUpdaterPeriod.LastCallbackTime = time.Now()
 		st.conn.progressUpdaterPeriod.LastQueryState = sr.Stats.State
 	}
-	return &sr, handleResponseError(resp.StatusCode, sr.Error)
 }
 
 type driverRows struct {
@@ -960,16 +1252,22 @@ type driverRows struct {
 	err          error
 	rowindex     int
 	columns      []string
+	queryColumns []queryColumn
 	coltype      []*typeConverter
 	data         []queryData
-	rowsAffected int64
+	updateType   string
+	updateCount  int64
+	stats        stmtStats
+	rows         int64
+
+	idleTimer *time.Timer // Cancels the query when the rows are left unread for too long
+	idleState int32
 
 	statsCh chan QueryProgressInfo
 	doneCh  chan struct{}
 }
 
 var _ driver.Rows = &driverRows{}
-var _ driver.Result = &driverRows{}
 var _ driver.RowsColumnTypeScanType = &driverRows{}
 var _ driver.RowsColumnTypeDatabaseTypeName = &driverRows{}
 var _ driver.RowsColumnTypeLength = &driverRows{}
@@ -977,31 +1275,42 @@ var _ driver.RowsColumnTypePrecisionScale = &driverRows{}
 
 // Close closes the rows iterator.
 func (qr *driverRows) Close() error {
+	if qr.stopIdleTimer() {
+		// The query was cancelled already.
+		qr.err = ErrIdleTimeout
+		return nil
+	}
 	if qr.err == sql.ErrNoRows || qr.err == io.EOF {
 		return nil
 	}
 	qr.err = io.EOF
-	hs := make(http.Header)
-	if qr.stmt.user != "" {
-		hs.Add(prestoUserHeader, qr.stmt.user)
-	}
-	req, err := qr.stmt.conn.newRequest("DELETE", qr.stmt.conn.baseURL+"/v1/query/"+url.PathEscape(qr.queryID), nil, hs)
-	if err != nil {
-		return err
+	if qr.queryID == "" {
+		// A resumed query did not return its first page yet.
+		return nil
 	}
-	ctx, cancel := context.WithTimeout(context.Background(), DefaultCancelQueryTimeout)
+	ctx, cancel := context.WithTimeout(context.Background(), qr.stmt.conn.timeouts.cancel)
 	defer cancel()
-	resp, err := qr.stmt.conn.roundTrip(ctx, req)
+	err := qr.stmt.conn.cancelQuery(qr.stmt.run.context(ctx), qr.stmt.baseURL, qr.queryID, qr.stmt.user)
+	qr.finish(nil, true)
 	if err != nil {
-		qferr, ok := err.(*ErrQueryFailed)
-		if ok && qferr.StatusCode == http.StatusNoContent {
-			qr.nextURI = ""
-			return nil
-		}
 		return err
 	}
-	resp.Body.Close()
-	return qr.err
+	qr.nextURI = ""
+	return nil
+}
+
+// finish reports the end of the query to the hooks of the statement.
+func (qr *driverRows) finish(err error, cancelled bool) {
+	if err == io.EOF {
+		err = nil
+	}
+	qr.stmt.run.finish(QueryDone{
+		QueryId:    qr.queryID,
+		Rows:       qr.rows,
+		QueryStats: qr.stats,
+		Err:        err,
+		Cancelled:  cancelled,
+	})
 }
 
 // Columns returns the names of the columns.
@@ -1019,7 +1328,7 @@ func (qr *driverRows) Columns() []string {
 }
 
 func (qr *driverRows) ColumnTypeDatabaseTypeName(index int) string {
-	typeName := qr.coltype[index].parsedType[0]
+	typeName := qr.coltype[index].signature.RawType
 	if typeName == "map" || typeName == "array" || typeName == "row" {
 		typeName = qr.coltype[index].typeName
 	}
@@ -1044,48 +1353,51 @@ func (qr *driverRows) ColumnTypePrecisionScale(index int) (precision, scale int6
 //
 // Next should return io.EOF when there are no more rows.
 func (qr *driverRows) Next(dest []driver.Value) error {
-	if qr.err != nil {
-		return qr.err
-	}
-	if qr.columns == nil || qr.rowindex >= len(qr.data) {
-		if qr.nextURI == "" {
-			qr.err = io.EOF
-			return qr.err
-		}
-		if err := qr.fetch(); err != nil {
-			qr.err = err
-			return err
-		}
-	}
-	if len(qr.coltype) == 0 {
-		qr.err = sql.ErrNoRows
-		return qr.err
+	row, err := qr.nextRow()
+	if err != nil {
+		return err
 	}
 	for i, v := range qr.coltype {
 		if i > len(dest)-1 {
 			break
 		}
-		vv, err := v.ConvertValue(qr.data[qr.rowindex][i])
+		vv, err := v.ConvertValue(row[i])
 		if err != nil {
 			qr.err = err
 			return err
 		}
 		dest[i] = vv
 	}
-	qr.rowindex++
 	return nil
 }
 
-// LastInsertId returns the database's auto-generated ID
-// after, for example, an INSERT into a table with primary
-// key.
-func (qr driverRows) LastInsertId() (int64, error) {
-	return 0, ErrOperationNotSupported
-}
-
-// RowsAffected returns the number of rows affected by the query.
-func (qr driverRows) RowsAffected() (int64, error) {
-	return qr.rowsAffected, nil
+// nextRow advances to the next row and returns it as decoded from JSON.
+func (qr *driverRows) nextRow() (queryData, error) {
+	if qr.stopIdleTimer() {
+		qr.err = ErrIdleTimeout
+	}
+	if qr.err != nil {
+		return nil, qr.err
+	}
+	if qr.columns == nil || qr.rowindex >= len(qr.data) {
+		if qr.nextURI == "" {
+			qr.err = io.EOF
+			return nil, qr.err
+		}
+		if err := qr.fetch(); err != nil {
+			qr.err = err
+			return nil, err
+		}
+	}
+	if len(qr.coltype) == 0 {
+		qr.err = sql.ErrNoRows
+		return nil, qr.err
+	}
+	row := qr.data[qr.rowindex]
+	qr.rowindex++
+	qr.rows++
+	qr.startIdleTimer()
+	return row, nil
 }
 
 type queryResponse struct {
@@ -1097,33 +1409,23 @@ type queryResponse struct {
 	Data             []queryData   `json:"data"`
 	Stats            stmtStats     `json:"stats"`
 	Error            stmtError     `json:"error"`
+	Warnings         []stmtWarning `json:"warnings"`
 	UpdateType       string        `json:"updateType"`
 	UpdateCount      int64         `json:"updateCount"`
 }
 
 type queryColumn struct {
-	Name          string        `json:"name"`
-	Type          string        `json:"type"`
-	TypeSignature typeSignature `json:"typeSignature"`
+	Name          string          `json:"name"`
+	Type          string          `json:"type"`
+	TypeSignature types.Signature `json:"typeSignature"`
 }
 
 type queryData []interface{}
 
-type namedTypeSignature struct {
-	FieldName rowFieldName `json:"fieldName"`
-}
-
-type rowFieldName struct {
-	Name string `json:"name"`
-}
-
-type typeSignature struct {
-	RawType   string         `json:"rawType"`
-	Arguments []typeArgument `json:"arguments"`
-}
-
-type typeKind string
+type typeKind = types.Kind
 
+// Kinds of type arguments. Type signatures are decoded by the types package,
+// which reports the kinds used by Trino, whatever the server.
 const (
 	KIND_TYPE                 = typeKind("TYPE")                 // Trino
 	KIND_TYPE_SIGNATURE       = typeKind("TYPE_SIGNATURE")       // Presto
@@ -1133,18 +1435,6 @@ const (
 	KIND_LONG_LITERAL         = typeKind("LONG_LITERAL")         // Presto
 )
 
-type typeArgument struct {
-	// Kind determines if the typeSignature, namedTypeSignature, or long field has a value
-	Kind  typeKind        `json:"kind"`
-	Value json.RawMessage `json:"value"`
-	// typeSignature decoded from Value when Kind is TYPE
-	typeSignature typeSignature
-	// namedTypeSignature decoded from Value when Kind is NAMED_TYPE
-	namedTypeSignature namedTypeSignature
-	// long decoded from Value when Kind is LONG
-	long int64
-}
-
 func handleResponseError(status int, respErr stmtError) error {
 	switch respErr.ErrorName {
 	case "":
@@ -1166,16 +1456,29 @@ func (qr *driverRows) fetch() error {
 		select {
 		case qresp = <-qr.stmt.queryResponses:
 			if qresp.ID == "" {
+				qr.finish(io.EOF, false)
 				return io.EOF
 			}
+			if qr.queryID == "" {
+				qr.queryID = qresp.ID
+			}
 			err = qr.initColumns(&qresp)
 			if err != nil {
+				qr.finish(err, false)
 				return err
 			}
 			qr.rowindex = 0
 			qr.data = qresp.Data
-			qr.rowsAffected = qresp.UpdateCount
+			qr.stmt.run.rowsReturned(len(qresp.Data))
+			qr.updateCount = qresp.UpdateCount
+			qr.stats = qresp.Stats
+			if qresp.UpdateType != "" {
+				qr.updateType = qresp.UpdateType
+			}
 			qr.scheduleProgressUpdate(qresp.ID, qresp.Stats)
+			qr.stmt.reportWarnings(qresp.ID, qresp.Warnings)
+			qr.stmt.partialCancel.setURI(qresp.PartialCancelURI)
+			qr.stmt.run.update(qresp.ID, qresp.Stats)
 			if len(qr.data) != 0 {
 				return nil
 			}
@@ -1184,7 +1487,9 @@ func (qr *driverRows) fetch() error {
 				// Channel was closed, which means the statement
 				// or rows were closed.
 				err = io.EOF
-			} else if err == context.Canceled {
+			}
+			qr.finish(err, false)
+			if err == context.Canceled || qr.ctx.Err() == context.DeadlineExceeded {
 				qr.Close()
 			}
 			qr.err = err
@@ -1193,51 +1498,15 @@ func (qr *driverRows) fetch() error {
 	}
 }
 
-func unmarshalArguments(signature *typeSignature) error {
-	for i, argument := range signature.Arguments {
-		var payload interface{}
-		switch argument.Kind {
-		case KIND_TYPE, KIND_TYPE_SIGNATURE:
-			payload = &(signature.Arguments[i].typeSignature)
-		case KIND_NAMED_TYPE, KIND_NAMED_TYPE_SIGNATURE:
-			payload = &(signature.Arguments[i].namedTypeSignature)
-		case KIND_LONG, KIND_LONG_LITERAL:
-			payload = &(signature.Arguments[i].long)
-		default:
-			return fmt.Errorf("unknown argument kind: %s", argument.Kind)
-		}
-		if err := json.Unmarshal(argument.Value, payload); err != nil {
-			return err
-		}
-
-		switch argument.Kind {
-		case KIND_TYPE, KIND_TYPE_SIGNATURE:
-			if err := unmarshalArguments(&(signature.Arguments[i].typeSignature)); err != nil {
-				return err
-			}
-		}
-	}
-	return nil
-}
-
 func (qr *driverRows) initColumns(qresp *queryResponse) error {
 	if qr.columns != nil || len(qresp.Columns) == 0 {
 		return nil
 	}
 	var err error
-	for i := range qresp.Columns {
-		err = unmarshalArguments(&(qresp.Columns[i].TypeSignature))
-		if err != nil {
-			return fmt.Errorf("error decoding column type signature: %w", err)
-		}
-	}
+	qr.queryColumns = qresp.Columns
 	qr.columns = make([]string, len(qresp.Columns))
 	qr.coltype = make([]*typeConverter, len(qresp.Columns))
 	for i, col := range qresp.Columns {
-		err = unmarshalArguments(&(qresp.Columns[i].TypeSignature))
-		if err != nil {
-			return fmt.Errorf("error decoding column type signature: %w", err)
-		}
 		qr.columns[i] = col.Name
 		qr.coltype[i], err = newTypeConverter(col.Type, col.TypeSignature)
 		if err != nil {
@@ -1275,12 +1544,12 @@ func (qr *driverRows) scheduleProgressUpdate(id string, stats stmtStats) {
 }
 
 type typeConverter struct {
-	typeName   string
-	parsedType []string
-	scanType   reflect.Type
-	precision  optionalInt64
-	scale      optionalInt64
-	size       optionalInt64
+	typeName  string
+	signature types.Signature
+	scanType  reflect.Type
+	precision optionalInt64
+	scale     optionalInt64
+	size      optionalInt64
 }
 
 type optionalInt64 struct {
@@ -1292,7 +1561,7 @@ func newOptionalInt64(value int64) optionalInt64 {
 	return optionalInt64{value: value, hasValue: true}
 }
 
-func argIsLong(signature typeSignature, argIdx int) bool {
+func argIsLong(signature types.Signature, argIdx int) bool {
 	if len(signature.Arguments) <= argIdx {
 		return false
 	}
@@ -1305,51 +1574,40 @@ func argIsLong(signature typeSignature, argIdx int) bool {
 	return false
 }
 
-func newTypeConverter(typeName string, signature typeSignature) (*typeConverter, error) {
+func newTypeConverter(typeName string, signature types.Signature) (*typeConverter, error) {
 	result := &typeConverter{
-		typeName:   typeName,
-		parsedType: getNestedTypes([]string{}, signature),
+		typeName:  typeName,
+		signature: signature,
 	}
 	var err error
-	result.scanType, err = getScanType(result.parsedType)
+	result.scanType, err = getScanType(signature.RawType)
 	if err != nil {
 		return nil, err
 	}
 	switch signature.RawType {
 	case "char", "varchar":
 		if argIsLong(signature, 0) {
-			result.size = newOptionalInt64(signature.Arguments[0].long)
+			result.size = newOptionalInt64(signature.Arguments[0].Long)
 		}
 	case "decimal":
 		if argIsLong(signature, 0) {
-			result.precision = newOptionalInt64(signature.Arguments[0].long)
+			result.precision = newOptionalInt64(signature.Arguments[0].Long)
 		}
 		if argIsLong(signature, 1) {
-			result.scale = newOptionalInt64(signature.Arguments[1].long)
+			result.scale = newOptionalInt64(signature.Arguments[1].Long)
 		}
 	case "time", "time with time zone", "timestamp", "timestamp with time zone":
 		if argIsLong(signature, 0) {
-			result.precision = newOptionalInt64(signature.Arguments[0].long)
+			result.precision = newOptionalInt64(signature.Arguments[0].Long)
 		}
 	}
 
 	return result, nil
 }
 
-func getNestedTypes(types []string, signature typeSignature) []string {
-	types = append(types, signature.RawType)
-	if len(signature.Arguments) == 1 {
-		switch signature.Arguments[0].Kind {
-		case KIND_TYPE, KIND_TYPE_SIGNATURE:
-			types = getNestedTypes(types, signature.Arguments[0].typeSignature)
-		}
-	}
-	return types
-}
-
-func getScanType(typeNames []string) (reflect.Type, error) {
+func getScanType(rawType string) (reflect.Type, error) {
 	var v interface{}
-	switch typeNames[0] {
+	switch rawType {
 	case "boolean":
 		v = sql.NullBool{}
 	case "json", "char", "varchar", "varbinary",
@@ -1374,7 +1632,7 @@ func getScanType(typeNames []string) (reflect.Type, error) {
 
 // ConvertValue implements the driver.ValueConverter interface.
 func (c *typeConverter) ConvertValue(v interface{}) (driver.Value, error) {
-	switch c.parsedType[0] {
+	switch c.signature.RawType {
 	case "boolean":
 		vv, err := scanNullBool(v)
 		if !vv.Valid {
@@ -1563,3 +1821,44 @@ type ProgressUpdater interface {
 	// Update the query progress, immediately when the query starts, when receiving data, and once when the query is finished.
 	Update(QueryProgressInfo)
 }
+
+// Warning is a warning reported by the server while running a query, such
+// as the use of deprecated syntax or a partial result.
+type Warning struct {
+	QueryId string
+	Code    int
+	Name    string
+	Message string
+}
+
+// WarningHandler receives the warnings reported for a query. It is passed to
+// a query as a named argument:
+//
+//	db.QueryContext(ctx, query, sql.Named("X-Presto-Warning-Callback", handler))
+type WarningHandler interface {
+	// HandleWarning is called once for every distinct warning, in the goroutine iterating over the results.
+	HandleWarning(Warning)
+}
+
+// reportWarnings passes the warnings not seen before to the warning handler.
+// The server repeats all warnings collected so far in every response.
+func (st *driverStmt) reportWarnings(queryID string, warnings []stmtWarning) {
+	if st.warningHandler == nil {
+		return
+	}
+	for _, w := range warnings {
+		if st.warnings[w] {
+			continue
+		}
+		if st.warnings == nil {
+			st.warnings = make(map[stmtWarning]bool)
+		}
+		st.warnings[w] = true
+		st.warningHandler.HandleWarning(Warning{
+			QueryId: queryID,
+			Code:    w.WarningCode.Code,
+			Name:    w.WarningCode.Name,
+			Message: w.Message,
+		})
+	}
+}
@@ -0,0 +1,56 @@
+// Copyright (c) Facebook, Inc. and its affiliates. All Rights Reserved
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package presto
+
+import (
+	"database/sql"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type warningRecorder []Warning
+
+func (r *warningRecorder) HandleWarning(w Warning) {
+	*r = append(*r, w)
+}
+
+func TestWarningsOfReusedStatement(t *testing.T) {
+	s := newFakeServer(t)
+	warning := stmtWarning{WarningCode: stmtWarningCode{Code: 1, Name: "DEPRECATED"}, Message: "deprecated"}
+	result := fakeRows
+	result.Warnings = []stmtWarning{warning}
+	s.result = func(string, http.Header) fakeResult { return result }
+	db := s.open(t, "")
+	db.SetMaxOpenConns(1)
+	st, err := db.Prepare("SELECT 1")
+	require.NoError(t, err)
+	defer st.Close()
+
+	var first, second warningRecorder
+	_, err = st.Exec(sql.Named(prestoWarningCallbackParam, &first))
+	require.NoError(t, err)
+	_, err = st.Exec(sql.Named(prestoWarningCallbackParam, &second))
+	require.NoError(t, err)
+	_, err = st.Exec()
+	require.NoError(t, err)
+
+	want := Warning{QueryId: "q0", Code: 1, Name: "DEPRECATED", Message: "deprecated"}
+	assert.Equal(t, warningRecorder{want}, first)
+	want.QueryId = "q1"
+	assert.Equal(t, warningRecorder{want}, second, "warnings of a previous execution must be reported again")
+}
@@ -0,0 +1,175 @@
+// Copyright (c) Facebook, Inc. and its affiliates. All Rights Reserved
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package prompresto exports the metrics of the Presto driver to Prometheus.
+//
+//	metrics := prompresto.NewMetrics(prompresto.Opts{})
+//	prometheus.MustRegister(metrics)
+//	connector, err := presto.NewConnector(dsn, presto.WithMetrics(metrics))
+//	...
+//	db := sql.OpenDB(connector)
+package prompresto
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/timescale/presto-go-client/presto"
+)
+
+// Opts configures the metrics.
+type Opts struct {
+	Namespace          string            // Namespace of the metrics (optional, default is presto_client)
+	Subsystem          string            // Subsystem of the metrics (optional)
+	ConstLabels        prometheus.Labels // Labels added to all the metrics (optional)
+	PageLatencyBuckets []float64         // Buckets of the page latency histogram, in seconds (optional, default is prometheus.DefBuckets)
+	QueueWaitBuckets   []float64         // Buckets of the queue wait histogram, in seconds (optional, default is prometheus.DefBuckets)
+}
+
+// Metrics is a prometheus.Collector receiving the measurements of the
+// driver. It implements the presto.Metrics and presto.QueueMetrics
+// interfaces.
+type Metrics struct {
+	queriesStarted   prometheus.Counter
+	queriesFailed    *prometheus.CounterVec
+	queriesCancelled prometheus.Counter
+	pageLatency      prometheus.Histogram
+	bytes            prometheus.Counter
+	rows             prometheus.Counter
+	retries          *prometheus.CounterVec
+	queueWait        prometheus.Histogram
+}
+
+var (
+	_ presto.Metrics       = &Metrics{}
+	_ presto.QueueMetrics  = &Metrics{}
+	_ prometheus.Collector = &Metrics{}
+)
+
+// NewMetrics returns metrics to register to a Prometheus registry and pass
+// to presto.WithMetrics.
+func NewMetrics(opts Opts) *Metrics {
+	if opts.Namespace == "" {
+		opts.Namespace = "presto_client"
+	}
+	if opts.PageLatencyBuckets == nil {
+		opts.PageLatencyBuckets = prometheus.DefBuckets
+	}
+	if opts.QueueWaitBuckets == nil {
+		opts.QueueWaitBuckets = prometheus.DefBuckets
+	}
+	counterOpts := func(name, help string) prometheus.CounterOpts {
+		return prometheus.CounterOpts{
+			Namespace:   opts.Namespace,
+			Subsystem:   opts.Subsystem,
+			Name:        name,
+			Help:        help,
+			ConstLabels: opts.ConstLabels,
+		}
+	}
+	return &Metrics{
+		queriesStarted: prometheus.NewCounter(counterOpts(
+			"queries_started_total", "Number of queries submitted.")),
+		queriesFailed: prometheus.NewCounterVec(counterOpts(
+			"queries_failed_total", "Number of failed queries, by error name."), []string{"error_name"}),
+		queriesCancelled: prometheus.NewCounter(counterOpts(
+			"queries_cancelled_total", "Number of cancelled queries.")),
+		pageLatency: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace:   opts.Namespace,
+			Subsystem:   opts.Subsystem,
+			Name:        "page_fetch_duration_seconds",
+			Help:        "Time to fetch a response polled from the next URI of a query.",
+			ConstLabels: opts.ConstLabels,
+			Buckets:     opts.PageLatencyBuckets,
+		}),
+		bytes: prometheus.NewCounter(counterOpts(
+			"page_bytes_total", "Number of bytes of the responses polled from the next URI of queries.")),
+		rows: prometheus.NewCounter(counterOpts(
+			"rows_returned_total", "Number of rows read by the client.")),
+		retries: prometheus.NewCounterVec(counterOpts(
+			"request_retries_total", "Number of requests retried because the server was busy, by status code."), []string{"code"}),
+		queueWait: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace:   opts.Namespace,
+			Subsystem:   opts.Subsystem,
+			Name:        "queue_wait_duration_seconds",
+			Help:        "Time queries waited for a slot to run, when max_concurrent_queries is set.",
+			ConstLabels: opts.ConstLabels,
+			Buckets:     opts.QueueWaitBuckets,
+		}),
+	}
+}
+
+func (m *Metrics) collectors() []prometheus.Collector {
+	return []prometheus.Collector{
+		m.queriesStarted,
+		m.queriesFailed,
+		m.queriesCancelled,
+		m.pageLatency,
+		m.bytes,
+		m.rows,
+		m.retries,
+		m.queueWait,
+	}
+}
+
+// Describe implements the prometheus.Collector interface.
+func (m *Metrics) Describe(ch chan<- *prometheus.Desc) {
+	for _, c := range m.collectors() {
+		c.Describe(ch)
+	}
+}
+
+// Collect implements the prometheus.Collector interface.
+func (m *Metrics) Collect(ch chan<- prometheus.Metric) {
+	for _, c := range m.collectors() {
+		c.Collect(ch)
+	}
+}
+
+// QueryStarted implements the presto.Metrics interface.
+func (m *Metrics) QueryStarted() {
+	m.queriesStarted.Inc()
+}
+
+// QueryFailed implements the presto.Metrics interface.
+func (m *Metrics) QueryFailed(errorName string) {
+	m.queriesFailed.WithLabelValues(errorName).Inc()
+}
+
+// QueryCancelled implements the presto.Metrics interface.
+func (m *Metrics) QueryCancelled() {
+	m.queriesCancelled.Inc()
+}
+
+// PageFetched implements the presto.Metrics interface.
+func (m *Metrics) PageFetched(latency time.Duration, bytes int64) {
+	m.pageLatency.Observe(latency.Seconds())
+	m.bytes.Add(float64(bytes))
+}
+
+// RowsReturned implements the presto.Metrics interface.
+func (m *Metrics) RowsReturned(rows int) {
+	m.rows.Add(float64(rows))
+}
+
+// RequestRetried implements the presto.Metrics interface.
+func (m *Metrics) RequestRetried(statusCode int) {
+	m.retries.WithLabelValues(strconv.Itoa(statusCode)).Inc()
+}
+
+// QueryQueued implements the presto.QueueMetrics interface.
+func (m *Metrics) QueryQueued(wait time.Duration) {
+	m.queueWait.Observe(wait.Seconds())
+}
@@ -0,0 +1,129 @@
+// Copyright (c) Facebook, Inc. and its affiliates. All Rights Reserved
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package prompresto
+
+import (
+	"database/sql"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/timescale/presto-go-client/presto"
+)
+
+// newServer returns a server returning two rows in a single page, which is
+// busy the first time it is polled, and failing the query SELECT fail.
+func newServer(t *testing.T) *httptest.Server {
+	var (
+		mu   sync.Mutex
+		busy = true
+	)
+	var s *httptest.Server
+	s = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var resp map[string]interface{}
+		switch r.URL.Path {
+		case "/v1/statement":
+			id := "ok"
+			if query, _ := io.ReadAll(r.Body); string(query) == "SELECT fail" {
+				id = "fail"
+			}
+			resp = map[string]interface{}{
+				"id":      id,
+				"nextUri": s.URL + "/v1/statement/" + id,
+				"stats":   map[string]interface{}{"state": "QUEUED"},
+			}
+		case "/v1/statement/ok":
+			mu.Lock()
+			retry := busy
+			busy = false
+			mu.Unlock()
+			if retry {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			resp = map[string]interface{}{
+				"id":      "ok",
+				"columns": []map[string]interface{}{{"name": "x", "type": "bigint", "typeSignature": "bigint"}},
+				"data":    [][]interface{}{{1}, {2}},
+				"stats":   map[string]interface{}{"state": "FINISHED"},
+			}
+		case "/v1/statement/fail":
+			resp = map[string]interface{}{
+				"id":    "fail",
+				"error": map[string]interface{}{"message": "failed", "errorName": "SYNTAX_ERROR"},
+				"stats": map[string]interface{}{"state": "FAILED"},
+			}
+		default:
+			http.NotFound(w, r)
+			return
+		}
+		json.NewEncoder(w).Encode(resp)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// sampleCount returns the number of observations of a histogram.
+func sampleCount(t *testing.T, registry *prometheus.Registry, name string) uint64 {
+	families, err := registry.Gather()
+	require.NoError(t, err)
+	for _, f := range families {
+		if f.GetName() == name {
+			return f.GetMetric()[0].GetHistogram().GetSampleCount()
+		}
+	}
+	t.Fatalf("no metric %s", name)
+	return 0
+}
+
+func TestMetrics(t *testing.T) {
+	s := newServer(t)
+	metrics := NewMetrics(Opts{ConstLabels: prometheus.Labels{"db": "test"}})
+	registry := prometheus.NewRegistry()
+	require.NoError(t, registry.Register(metrics))
+	connector, err := presto.NewConnector("http://user@"+s.Listener.Addr().String()+"?max_concurrent_queries=1", presto.WithMetrics(metrics))
+	require.NoError(t, err)
+	db := sql.OpenDB(connector)
+	defer db.Close()
+
+	rows, err := db.Query("SELECT x")
+	require.NoError(t, err)
+	for rows.Next() {
+	}
+	require.NoError(t, rows.Err())
+	_, err = db.Exec("SELECT fail")
+	assert.Error(t, err)
+
+	assert.Equal(t, 2.0, testutil.ToFloat64(metrics.queriesStarted))
+	assert.Equal(t, 1.0, testutil.ToFloat64(metrics.queriesFailed.WithLabelValues("SYNTAX_ERROR")))
+	assert.Equal(t, 0.0, testutil.ToFloat64(metrics.queriesCancelled))
+	assert.Equal(t, 2.0, testutil.ToFloat64(metrics.rows))
+	assert.Equal(t, 1.0, testutil.ToFloat64(metrics.retries.WithLabelValues("503")))
+	assert.Greater(t, testutil.ToFloat64(metrics.bytes), 0.0)
+	assert.Equal(t, uint64(2), sampleCount(t, registry, "presto_client_page_fetch_duration_seconds"))
+	assert.Equal(t, uint64(2), sampleCount(t, registry, "presto_client_queue_wait_duration_seconds"))
+	assert.Equal(t, 8, testutil.CollectAndCount(metrics))
+
+	problems, err := testutil.GatherAndLint(registry)
+	require.NoError(t, err)
+	assert.Empty(t, problems)
+}
@@ -0,0 +1,306 @@
+// Copyright (c) Facebook, Inc. and its affiliates. All Rights Reserved
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package presto
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// QuerySummary summarizes what a query did and consumed, as reported by the
+// coordinator running it.
+type QuerySummary struct {
+	QueryId string
+	State   string
+	Query   string
+	User    string
+	Error   *QueryError // Error of the query, nil unless it failed
+
+	CreateTime    time.Time
+	EndTime       time.Time // Zero until the query is done
+	ElapsedTime   time.Duration
+	QueuedTime    time.Duration
+	ExecutionTime time.Duration
+	CPUTime       time.Duration // Cumulative CPU time of all the tasks of the query
+	ScheduledTime time.Duration
+
+	PeakUserMemory       int64   // Bytes
+	PeakTotalMemory      int64   // Bytes
+	CumulativeUserMemory float64 // Byte-milliseconds
+
+	InputRows   int64
+	InputBytes  int64
+	OutputRows  int64
+	OutputBytes int64
+
+	OutputStage *StageSummary // Root of the tree of the stages of the query, nil before the query is planned
+}
+
+// QueryError describes the error of a failed query.
+type QueryError struct {
+	Code    int
+	Name    string
+	Type    string
+	Message string
+}
+
+// StageSummary summarizes what a stage of a query did and consumed.
+type StageSummary struct {
+	StageId       string
+	State         string
+	CPUTime       time.Duration
+	ScheduledTime time.Duration
+	InputRows     int64
+	InputBytes    int64
+	OutputRows    int64
+	OutputBytes   int64
+	SubStages     []StageSummary
+}
+
+// QueryInfo returns the summary of a query, which can have been run by
+// another process. The server forgets about queries some time after they
+// are done, so the summary of a query must be fetched soon after it ends;
+// sql.ErrNoRows is returned for unknown queries. When the DSN lists several
+// coordinators, the query is looked up on each of them.
+func QueryInfo(ctx context.Context, db *sql.DB, queryID string) (*QuerySummary, error) {
+	var summary *QuerySummary
+	err := withConn(ctx, db, func(c *Conn) error {
+		var firstErr error
+		for _, baseURL := range c.coordinators.urls {
+			var info queryInfo
+			found, err := c.getQuery(ctx, baseURL, queryID, &info)
+			if err != nil {
+				if firstErr == nil {
+					firstErr = err
+				}
+				continue
+			}
+			if found {
+				summary, err = info.summary()
+				return err
+			}
+		}
+		if firstErr != nil {
+			return firstErr
+		}
+		return sql.ErrNoRows
+	})
+	if err != nil {
+		return nil, err
+	}
+	return summary, nil
+}
+
+// queryInfo is the part of the response of /v1/query/{id} used for the
+// summary of a query.
+type queryInfo struct {
+	QueryID string `json:"queryId"`
+	State   string `json:"state"`
+	Query   string `json:"query"`
+	Session struct {
+		User string `json:"user"`
+	} `json:"session"`
+	ErrorCode *struct {
+		Code int    `json:"code"`
+		Name string `json:"name"`
+		Type string `json:"type"`
+	} `json:"errorCode"`
+	FailureInfo *struct {
+		Message string `json:"message"`
+	} `json:"failureInfo"`
+	QueryStats struct {
+		CreateTime                 time.Time `json:"createTime"`
+		EndTime                    time.Time `json:"endTime"`
+		ElapsedTime                string    `json:"elapsedTime"`
+		QueuedTime                 string    `json:"queuedTime"`
+		ExecutionTime              string    `json:"executionTime"`
+		TotalCPUTime               string    `json:"totalCpuTime"`
+		TotalScheduledTime         string    `json:"totalScheduledTime"`
+		PeakUserMemoryReservation  dataSize  `json:"peakUserMemoryReservation"`
+		PeakTotalMemoryReservation dataSize  `json:"peakTotalMemoryReservation"`
+		CumulativeUserMemory       float64   `json:"cumulativeUserMemory"`
+		RawInputPositions          int64     `json:"rawInputPositions"`
+		RawInputDataSize           dataSize  `json:"rawInputDataSize"`
+		OutputPositions            int64     `json:"outputPositions"`
+		OutputDataSize             dataSize  `json:"outputDataSize"`
+	} `json:"queryStats"`
+	OutputStage *stageInfo `json:"outputStage"`
+}
+
+// stageInfo is a stage in the response of /v1/query/{id}. Trino reports the
+// statistics of a stage in stageStats, Presto in the latest attempt to run
+// the stage.
+type stageInfo struct {
+	StageID                    string      `json:"stageId"`
+	State                      string      `json:"state"`
+	StageStats                 *stageStats `json:"stageStats"`
+	LatestAttemptExecutionInfo struct {
+		State string      `json:"state"`
+		Stats *stageStats `json:"stats"`
+	} `json:"latestAttemptExecutionInfo"`
+	SubStages []stageInfo `json:"subStages"`
+}
+
+type stageStats struct {
+	TotalCPUTime       string   `json:"totalCpuTime"`
+	TotalScheduledTime string   `json:"totalScheduledTime"`
+	RawInputPositions  int64    `json:"rawInputPositions"`
+	RawInputDataSize   dataSize `json:"rawInputDataSize"`
+	OutputPositions    int64    `json:"outputPositions"`
+	OutputDataSize     dataSize `json:"outputDataSize"`
+}
+
+func (info *queryInfo) summary() (*QuerySummary, error) {
+	qs := &info.QueryStats
+	s := &QuerySummary{
+		QueryId:              info.QueryID,
+		State:                info.State,
+		Query:                info.Query,
+		User:                 info.Session.User,
+		CreateTime:           qs.CreateTime,
+		EndTime:              qs.EndTime,
+		PeakUserMemory:       int64(qs.PeakUserMemoryReservation),
+		PeakTotalMemory:      int64(qs.PeakTotalMemoryReservation),
+		CumulativeUserMemory: qs.CumulativeUserMemory,
+		InputRows:            qs.RawInputPositions,
+		InputBytes:           int64(qs.RawInputDataSize),
+		OutputRows:           qs.OutputPositions,
+		OutputBytes:          int64(qs.OutputDataSize),
+	}
+	if info.ErrorCode != nil {
+		s.Error = &QueryError{
+			Code: info.ErrorCode.Code,
+			Name: info.ErrorCode.Name,
+			Type: info.ErrorCode.Type,
+		}
+		if info.FailureInfo != nil {
+			s.Error.Message = info.FailureInfo.Message
+		}
+	}
+	for _, d := range []struct {
+		dst *time.Duration
+		src string
+	}{
+		{&s.ElapsedTime, qs.ElapsedTime},
+		{&s.QueuedTime, qs.QueuedTime},
+		{&s.ExecutionTime, qs.ExecutionTime},
+		{&s.CPUTime, qs.TotalCPUTime},
+		{&s.ScheduledTime, qs.TotalScheduledTime},
+	} {
+		var err error
+		if *d.dst, err = parseDuration(d.src); err != nil {
+			return nil, err
+		}
+	}
+	if info.OutputStage != nil {
+		stage, err := info.OutputStage.summary()
+		if err != nil {
+			return nil, err
+		}
+		s.OutputStage = &stage
+	}
+	return s, nil
+}
+
+func (info *stageInfo) summary() (StageSummary, error) {
+	s := StageSummary{
+		StageId: info.StageID,
+		State:   info.State,
+	}
+	if s.State == "" {
+		s.State = info.LatestAttemptExecutionInfo.State
+	}
+	stats := info.StageStats
+	if stats == nil {
+		stats = info.LatestAttemptExecutionInfo.Stats
+	}
+	if stats != nil {
+		var err error
+		if s.CPUTime, err = parseDuration(stats.TotalCPUTime); err != nil {
+			return s, err
+		}
+		if s.ScheduledTime, err = parseDuration(stats.TotalScheduledTime); err != nil {
+			return s, err
+		}
+		s.InputRows = stats.RawInputPositions
+		s.InputBytes = int64(stats.RawInputDataSize)
+		s.OutputRows = stats.OutputPositions
+		s.OutputBytes = int64(stats.OutputDataSize)
+	}
+	for _, sub := range info.SubStages {
+		subStage, err := sub.summary()
+		if err != nil {
+			return s, err
+		}
+		s.SubStages = append(s.SubStages, subStage)
+	}
+	return s, nil
+}
+
+// dataSize is a data size reported by the server, either as a number of
+// bytes or as a string with a unit, such as 1.50MB.
+type dataSize int64
+
+// dataSizeUnits are the units of the data sizes reported by the server.
+var dataSizeUnits = map[string]float64{
+	"B":  1,
+	"kB": 1 << 10,
+	"MB": 1 << 20,
+	"GB": 1 << 30,
+	"TB": 1 << 40,
+	"PB": 1 << 50,
+}
+
+func (d *dataSize) UnmarshalJSON(b []byte) error {
+	var v interface{}
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	switch x := v.(type) {
+	case nil:
+		*d = 0
+	case float64:
+		*d = dataSize(x)
+	case string:
+		size, err := parseDataSize(x)
+		if err != nil {
+			return err
+		}
+		*d = size
+	default:
+		return fmt.Errorf("presto: malformed data size %s", b)
+	}
+	return nil
+}
+
+// parseDataSize parses a data size with a unit, such as 1.50MB.
+func parseDataSize(s string) (dataSize, error) {
+	i := strings.IndexFunc(s, func(r rune) bool {
+		return (r < '0' || r > '9') && r != '.'
+	})
+	if i > 0 {
+		if unit, ok := dataSizeUnits[strings.TrimSpace(s[i:])]; ok {
+			if v, err := strconv.ParseFloat(s[:i], 64); err == nil {
+				return dataSize(v * unit), nil
+			}
+		}
+	}
+	return 0, fmt.Errorf("presto: malformed data size %q", s)
+}
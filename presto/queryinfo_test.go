@@ -0,0 +1,149 @@
+// Copyright (c) Facebook, Inc. and its affiliates. All Rights Reserved
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package presto
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDataSize(t *testing.T) {
+	for _, tt := range []struct {
+		json string
+		want dataSize
+	}{
+		{`null`, 0},
+		{`1024`, 1024},
+		{`"12B"`, 12},
+		{`"1.50kB"`, 1536},
+		{`"2MB"`, 2 << 20},
+		{`"0.50GB"`, 1 << 29},
+		{`"1.00TB"`, 1 << 40},
+		{`"1.00PB"`, 1 << 50},
+		{`"3.00 MB"`, 3 << 20},
+	} {
+		var d dataSize
+		require.NoError(t, json.Unmarshal([]byte(tt.json), &d), tt.json)
+		assert.Equal(t, tt.want, d, tt.json)
+	}
+	for _, s := range []string{`"1.00"`, `"MB"`, `"1.00mb"`, `"-1B"`, `true`, `[1]`} {
+		var d dataSize
+		assert.Error(t, json.Unmarshal([]byte(s), &d), s)
+	}
+}
+
+func TestQueryInfo(t *testing.T) {
+	s := newFakeServer(t)
+	s.handler = func(w http.ResponseWriter, r *http.Request) bool {
+		if r.URL.Path != "/v1/query/failed" {
+			return false
+		}
+		w.Write([]byte(`{
+			"queryId": "failed",
+			"state": "FAILED",
+			"query": "SELECT x",
+			"session": {"user": "user"},
+			"errorCode": {"code": 1, "name": "SYNTAX_ERROR", "type": "USER_ERROR"},
+			"failureInfo": {"message": "line 1:8: Column 'x' cannot be resolved"},
+			"queryStats": {
+				"createTime": "2026-10-18T10:00:00.000Z",
+				"endTime": "2026-10-18T10:00:01.500Z",
+				"elapsedTime": "1.50s",
+				"queuedTime": "10.00ms",
+				"executionTime": "1.40s",
+				"totalCpuTime": "2.00s",
+				"totalScheduledTime": "3.00s",
+				"peakUserMemoryReservation": "1.00MB",
+				"peakTotalMemoryReservation": 2048,
+				"cumulativeUserMemory": 12.5,
+				"rawInputPositions": 10,
+				"rawInputDataSize": "1.00kB",
+				"outputPositions": 1,
+				"outputDataSize": "8B"
+			},
+			"outputStage": {
+				"stageId": "failed.0",
+				"latestAttemptExecutionInfo": {
+					"state": "FAILED",
+					"stats": {"totalCpuTime": "1.00s", "totalScheduledTime": "1.00s", "outputPositions": 1, "outputDataSize": "8B"}
+				},
+				"subStages": [{
+					"stageId": "failed.1",
+					"state": "FINISHED",
+					"stageStats": {"totalCpuTime": "1.00s", "totalScheduledTime": "2.00s", "rawInputPositions": 10, "rawInputDataSize": "1.00kB"}
+				}]
+			}
+		}`))
+		return true
+	}
+	db := s.open(t, "")
+	ctx := context.Background()
+
+	summary, err := QueryInfo(ctx, db, "failed")
+	require.NoError(t, err)
+	createTime := time.Date(2026, 10, 18, 10, 0, 0, 0, time.UTC)
+	assert.Equal(t, &QuerySummary{
+		QueryId: "failed",
+		State:   "FAILED",
+		Query:   "SELECT x",
+		User:    "user",
+		Error: &QueryError{
+			Code:    1,
+			Name:    "SYNTAX_ERROR",
+			Type:    "USER_ERROR",
+			Message: "line 1:8: Column 'x' cannot be resolved",
+		},
+		CreateTime:           createTime,
+		EndTime:              createTime.Add(1500 * time.Millisecond),
+		ElapsedTime:          1500 * time.Millisecond,
+		QueuedTime:           10 * time.Millisecond,
+		ExecutionTime:        1400 * time.Millisecond,
+		CPUTime:              2 * time.Second,
+		ScheduledTime:        3 * time.Second,
+		PeakUserMemory:       1 << 20,
+		PeakTotalMemory:      2048,
+		CumulativeUserMemory: 12.5,
+		InputRows:            10,
+		InputBytes:           1024,
+		OutputRows:           1,
+		OutputBytes:          8,
+		OutputStage: &StageSummary{
+			StageId:       "failed.0",
+			State:         "FAILED",
+			CPUTime:       time.Second,
+			ScheduledTime: time.Second,
+			OutputRows:    1,
+			OutputBytes:   8,
+			SubStages: []StageSummary{{
+				StageId:       "failed.1",
+				State:         "FINISHED",
+				CPUTime:       time.Second,
+				ScheduledTime: 2 * time.Second,
+				InputRows:     10,
+				InputBytes:    1024,
+			}},
+		},
+	}, summary)
+
+	_, err = QueryInfo(ctx, db, "unknown")
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+}
@@ -0,0 +1,104 @@
+// Copyright (c) Facebook, Inc. and its affiliates. All Rights Reserved
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package presto
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+)
+
+// Result is the result of a statement run with ExecContext.
+//
+// The database/sql package hides the driver result behind its own sql.Result,
+// use Exec to get hold of it.
+type Result struct {
+	QueryId     string    // ID of the query that ran the statement
+	UpdateType  string    // Type of the statement, e.g. INSERT, CREATE TABLE or SET SESSION
+	UpdateCount int64     // Number of rows affected, for statements that report one
+	QueryStats  stmtStats // Final statistics of the query
+}
+
+var _ driver.Result = &Result{}
+
+// LastInsertId returns the database's auto-generated ID
+// after, for example, an INSERT into a table with primary
+// key.
+func (r *Result) LastInsertId() (int64, error) {
+	return 0, ErrOperationNotSupported
+}
+
+// RowsAffected returns the number of rows affected by the query.
+func (r *Result) RowsAffected() (int64, error) {
+	return r.UpdateCount, nil
+}
+
+// Exec runs a statement on conn, like conn.ExecContext, and returns the
+// Result reported by the driver:
+//
+//	conn, err := db.Conn(ctx)
+//	...
+//	res, err := presto.Exec(ctx, conn, "CREATE TABLE t AS SELECT * FROM s")
+//	fmt.Println(res.QueryId, res.UpdateType, res.UpdateCount)
+func Exec(ctx context.Context, conn *sql.Conn, query string, args ...interface{}) (*Result, error) {
+	var res *Result
+	err := conn.Raw(func(driverConn interface{}) error {
+		c, ok := driverConn.(*Conn)
+		if !ok {
+			return fmt.Errorf("presto: unexpected driver connection %T", driverConn)
+		}
+		st := &driverStmt{conn: c, query: query}
+		defer st.Close()
+		nvs, err := namedValues(st, args)
+		if err != nil {
+			return err
+		}
+		r, err := st.ExecContext(ctx, nvs)
+		if err != nil {
+			return err
+		}
+		res = r.(*Result)
+		return nil
+	})
+	return res, err
+}
+
+// namedValues converts arguments the way database/sql does before passing
+// them to a statement: sql.NamedArg values keep their name, and values not
+// accepted by the statement go through the default parameter converter.
+func namedValues(st *driverStmt, args []interface{}) ([]driver.NamedValue, error) {
+	nvs := make([]driver.NamedValue, len(args))
+	for i, arg := range args {
+		nv := driver.NamedValue{Ordinal: i + 1, Value: arg}
+		if named, ok := arg.(sql.NamedArg); ok {
+			nv.Name = named.Name
+			nv.Value = named.Value
+		}
+		switch err := st.CheckNamedValue(&nv); err {
+		case nil:
+		case driver.ErrSkip:
+			v, err := driver.DefaultParameterConverter.ConvertValue(nv.Value)
+			if err != nil {
+				return nil, fmt.Errorf("presto: converting argument %d: %w", nv.Ordinal, err)
+			}
+			nv.Value = v
+		default:
+			return nil, err
+		}
+		nvs[i] = nv
+	}
+	return nvs, nil
+}
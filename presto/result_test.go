@@ -0,0 +1,75 @@
+// Copyright (c) Facebook, Inc. and its affiliates. All Rights Reserved
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package presto
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// upperValuer is a driver.Valuer converting a string to upper case.
+type upperValuer string
+
+func (v upperValuer) Value() (driver.Value, error) {
+	return strings.ToUpper(string(v)), nil
+}
+
+func TestExec(t *testing.T) {
+	s := newFakeServer(t)
+	s.result = func(query string, header http.Header) fakeResult {
+		return fakeResult{
+			UpdateType:  "DELETE",
+			UpdateCount: 3,
+			Stats:       stmtStats{Nodes: 2, ProcessedRows: 10, ProcessedBytes: 100},
+		}
+	}
+	db := s.open(t, "")
+	ctx := context.Background()
+	conn, err := db.Conn(ctx)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	res, err := Exec(ctx, conn, "DELETE FROM t WHERE id = :id AND name = :name", sql.Named("name", upperValuer("a")), sql.Named("id", 7))
+	require.NoError(t, err)
+	assert.Equal(t, &Result{
+		QueryId:     "q0",
+		UpdateType:  "DELETE",
+		UpdateCount: 3,
+		QueryStats:  stmtStats{State: "FINISHED", Nodes: 2, ProcessedRows: 10, ProcessedBytes: 100},
+	}, res)
+	n, err := res.RowsAffected()
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), n)
+
+	res, err = Exec(ctx, conn, "DELETE FROM t WHERE name = ? AND id = ?", upperValuer("b"), int32(8))
+	require.NoError(t, err)
+	assert.Equal(t, "q1", res.QueryId)
+
+	posts := s.received("POST", "/v1/statement")
+	require.Len(t, posts, 2)
+	assert.Equal(t, "EXECUTE "+preparedStatementName+" USING 7, 'A'", posts[0].Body)
+	assert.Equal(t, "EXECUTE "+preparedStatementName+" USING 'B', 8", posts[1].Body)
+
+	_, err = Exec(ctx, conn, "DELETE FROM t WHERE id = ?", struct{}{})
+	assert.Error(t, err)
+	assert.Len(t, s.received("POST", "/v1/statement"), 2)
+}
@@ -0,0 +1,456 @@
+// Copyright (c) Facebook, Inc. and its affiliates. All Rights Reserved
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package presto
+
+import (
+	"container/list"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/timescale/presto-go-client/presto/types"
+)
+
+// queryer is implemented by *sql.DB, *sql.Conn and *sql.Tx.
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// ScanStruct copies the columns of the current row of rows into the fields
+// of the struct pointed to by dest.
+//
+// Columns are matched to fields by the name given in a presto struct tag, or
+// else by field name, ignoring case. Fields tagged with presto:"-" are
+// skipped, and so are columns without a matching field. The fields of
+// embedded structs are matched as if they were fields of the outer struct.
+//
+// ROW values can be copied to structs, whose fields are matched to the row
+// fields the same way, ARRAY values to slices and MAP values to maps. Dates
+// and timestamps can be copied to time.Time fields. Pointer fields are set to
+// nil for NULL values. Fields implementing sql.Scanner are scanned with the
+// value that rows.Scan would have used.
+func ScanStruct(rows *sql.Rows, dest interface{}) error {
+	v := reflect.ValueOf(dest)
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("presto: ScanStruct destination must be a non-nil pointer to a struct, got %T", dest)
+	}
+	s, err := newStructScanner(rows, v.Elem().Type())
+	if err != nil {
+		return err
+	}
+	return s.scan(rows, v.Elem())
+}
+
+// QueryStructs runs a query and returns its rows copied to values of type T,
+// which must be a struct type, as done by ScanStruct. db is usually a
+// *sql.DB, but can also be a *sql.Conn or a *sql.Tx.
+func QueryStructs[T any](ctx context.Context, db queryer, query string, args ...interface{}) ([]T, error) {
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	t := reflect.TypeOf((*T)(nil)).Elem()
+	if t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("presto: QueryStructs type must be a struct, got %s", t)
+	}
+	s, err := newStructScanner(rows, t)
+	if err != nil {
+		return nil, err
+	}
+	var result []T
+	for rows.Next() {
+		var item T
+		if err := s.scan(rows, reflect.ValueOf(&item).Elem()); err != nil {
+			return nil, err
+		}
+		result = append(result, item)
+	}
+	return result, rows.Err()
+}
+
+type structScanner struct {
+	columns []string
+	types   []types.Signature
+	fields  [][]int // index of the field of each column, nil if there is none
+}
+
+// maxStructScanners is the number of scanners kept in structScanners, which
+// gets a new entry for every set of columns scanned into a struct type.
+const maxStructScanners = 256
+
+// structScanners caches the scanners by struct type and columns, since
+// ScanStruct is called for every row.
+var structScanners = &structScannerCache{
+	entries: make(map[structScannerKey]*list.Element),
+	lru:     list.New(),
+}
+
+type structScannerKey struct {
+	t       reflect.Type
+	columns string // Names and types of the columns
+}
+
+// structScannerCache keeps the most recently used scanners.
+type structScannerCache struct {
+	mu      sync.Mutex
+	entries map[structScannerKey]*list.Element
+	lru     *list.List // *structScannerEntry, most recently used first
+}
+
+type structScannerEntry struct {
+	key     structScannerKey
+	scanner *structScanner
+}
+
+func (c *structScannerCache) get(key structScannerKey) *structScanner {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[key]
+	if !ok {
+		return nil
+	}
+	c.lru.MoveToFront(e)
+	return e.Value.(*structScannerEntry).scanner
+}
+
+func (c *structScannerCache) put(key structScannerKey, s *structScanner) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.entries[key]; ok {
+		c.lru.MoveToFront(e)
+		return
+	}
+	c.entries[key] = c.lru.PushFront(&structScannerEntry{key: key, scanner: s})
+	if c.lru.Len() > maxStructScanners {
+		oldest := c.lru.Back()
+		c.lru.Remove(oldest)
+		delete(c.entries, oldest.Value.(*structScannerEntry).key)
+	}
+}
+
+func newStructScanner(rows *sql.Rows, t reflect.Type) (*structScanner, error) {
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	columnTypes, err := rows.ColumnTypes()
+	if err != nil {
+		return nil, err
+	}
+	typeNames := make([]string, len(columns))
+	var key strings.Builder
+	for i, col := range columns {
+		typeNames[i] = strings.ToLower(columnTypes[i].DatabaseTypeName())
+		key.WriteString(col)
+		key.WriteByte(0)
+		key.WriteString(typeNames[i])
+		key.WriteByte(0)
+	}
+	cacheKey := structScannerKey{t: t, columns: key.String()}
+	if s := structScanners.get(cacheKey); s != nil {
+		return s, nil
+	}
+	fields := structFields(t)
+	s := &structScanner{
+		columns: columns,
+		types:   make([]types.Signature, len(columns)),
+		fields:  make([][]int, len(columns)),
+	}
+	for i, col := range columns {
+		s.fields[i] = fields[strings.ToLower(col)]
+		if s.fields[i] == nil {
+			continue
+		}
+		s.types[i], err = types.Parse(typeNames[i])
+		if err != nil {
+			return nil, err
+		}
+	}
+	structScanners.put(cacheKey, s)
+	return s, nil
+}
+
+func (s *structScanner) scan(rows *sql.Rows, dest reflect.Value) error {
+	values := make([]interface{}, len(s.columns))
+	for i := range values {
+		values[i] = new(interface{})
+	}
+	if err := rows.Scan(values...); err != nil {
+		return err
+	}
+	for i, index := range s.fields {
+		if index == nil {
+			continue
+		}
+		v := *(values[i].(*interface{}))
+		field := dest.FieldByIndex(index)
+		if scanner, ok := field.Addr().Interface().(sql.Scanner); ok {
+			if err := scanner.Scan(v); err != nil {
+				return fmt.Errorf("presto: column %s: %w", s.columns[i], err)
+			}
+			continue
+		}
+		switch s.types[i].RawType {
+		case "array", "map", "row":
+			if v == "" {
+				// The driver returns NULL nested values as empty strings.
+				v = nil
+			}
+		}
+		native, err := decodeValue(s.types[i], jsonValue(v))
+		if err != nil {
+			return fmt.Errorf("presto: column %s: %w", s.columns[i], err)
+		}
+		if err := assignValue(field, s.types[i], native); err != nil {
+			return fmt.Errorf("presto: column %s: %w", s.columns[i], err)
+		}
+	}
+	return nil
+}
+
+// jsonValue returns the value the server sent for a driver value, so it can
+// be decoded like the values read by Client.
+func jsonValue(v interface{}) interface{} {
+	switch x := v.(type) {
+	case int64:
+		return json.Number(strconv.FormatInt(x, 10))
+	case float64:
+		switch {
+		case math.IsNaN(x):
+			return "NaN"
+		case math.IsInf(x, 1):
+			return "Infinity"
+		case math.IsInf(x, -1):
+			return "-Infinity"
+		}
+		return json.Number(strconv.FormatFloat(x, 'g', -1, 64))
+	}
+	return v
+}
+
+var structFieldsCache sync.Map // map[reflect.Type]map[string][]int
+
+// structFields returns the index of the fields of a struct type by lower case
+// column name.
+func structFields(t reflect.Type) map[string][]int {
+	if fields, ok := structFieldsCache.Load(t); ok {
+		return fields.(map[string][]int)
+	}
+	fields := make(map[string][]int)
+	addStructFields(fields, t, nil)
+	structFieldsCache.Store(t, fields)
+	return fields
+}
+
+func addStructFields(fields map[string][]int, t reflect.Type, parent []int) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		index := append(append([]int(nil), parent...), i)
+		tag := f.Tag.Get("presto")
+		if tag == "-" {
+			continue
+		}
+		if f.Anonymous && tag == "" && f.Type.Kind() == reflect.Struct {
+			addStructFields(fields, f.Type, index)
+			continue
+		}
+		if f.PkgPath != "" {
+			// unexported
+			continue
+		}
+		name := tag
+		if name == "" {
+			name = f.Name
+		}
+		name = strings.ToLower(name)
+		if _, ok := fields[name]; !ok || len(index) < len(fields[name]) {
+			fields[name] = index
+		}
+	}
+}
+
+// assignValue copies a value returned by decodeValue to dst.
+func assignValue(dst reflect.Value, sig types.Signature, v interface{}) error {
+	if v == nil {
+		dst.Set(reflect.Zero(dst.Type()))
+		return nil
+	}
+	switch dst.Kind() {
+	case reflect.Ptr:
+		elem := reflect.New(dst.Type().Elem())
+		if err := assignValue(elem.Elem(), sig, v); err != nil {
+			return err
+		}
+		dst.Set(elem)
+		return nil
+	case reflect.Interface:
+		if reflect.TypeOf(v).AssignableTo(dst.Type()) {
+			dst.Set(reflect.ValueOf(v))
+			return nil
+		}
+	case reflect.Struct:
+		if fields, ok := v.([]interface{}); ok && sig.RawType == "row" {
+			return assignRow(dst, sig, fields)
+		}
+	case reflect.Slice:
+		if items, ok := v.([]interface{}); ok {
+			slice := reflect.MakeSlice(dst.Type(), len(items), len(items))
+			for i, item := range items {
+				if err := assignValue(slice.Index(i), itemType(sig, i), item); err != nil {
+					return err
+				}
+			}
+			dst.Set(slice)
+			return nil
+		}
+	case reflect.Array:
+		if items, ok := v.([]interface{}); ok && len(items) == dst.Len() {
+			for i, item := range items {
+				if err := assignValue(dst.Index(i), itemType(sig, i), item); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+	case reflect.Map:
+		if entries, ok := v.(map[interface{}]interface{}); ok {
+			m := reflect.MakeMapWithSize(dst.Type(), len(entries))
+			keyType, valueType := sig.Elem(0), sig.Elem(1)
+			for k, e := range entries {
+				key := reflect.New(dst.Type().Key()).Elem()
+				if err := assignValue(key, keyType, k); err != nil {
+					return err
+				}
+				value := reflect.New(dst.Type().Elem()).Elem()
+				if err := assignValue(value, valueType, e); err != nil {
+					return err
+				}
+				m.SetMapIndex(key, value)
+			}
+			dst.Set(m)
+			return nil
+		}
+	}
+	src := reflect.ValueOf(v)
+	if src.Type().AssignableTo(dst.Type()) {
+		dst.Set(src)
+		return nil
+	}
+	if convertible(src.Kind(), dst.Kind()) {
+		if !fits(src, dst.Type()) {
+			return fmt.Errorf("cannot assign %v (%T) to %s without losing precision", v, v, dst.Type())
+		}
+		dst.Set(src.Convert(dst.Type()))
+		return nil
+	}
+	return fmt.Errorf("cannot assign %v (%T) to %s", v, v, dst.Type())
+}
+
+// assignRow copies the fields of a row to the fields of a struct with the
+// same name, ignoring case, or else to the struct fields in order.
+func assignRow(dst reflect.Value, sig types.Signature, values []interface{}) error {
+	fields := structFields(dst.Type())
+	for i, v := range values {
+		var index []int
+		if i < len(sig.Arguments) {
+			name := sig.Arguments[i].FieldName
+			index = fields[strings.ToLower(name)]
+		}
+		if index == nil {
+			if i >= dst.NumField() || dst.Type().Field(i).PkgPath != "" {
+				continue
+			}
+			index = []int{i}
+		}
+		if err := assignValue(dst.FieldByIndex(index), sig.Elem(i), v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// itemType returns the type of the i-th item of an array or a row.
+func itemType(sig types.Signature, i int) types.Signature {
+	if sig.RawType == "row" {
+		return sig.Elem(i)
+	}
+	return sig.Elem(0)
+}
+
+// fits reports whether a number converted to the type t keeps its value.
+// Large integers converted to floating point numbers are rounded, like
+// database/sql does.
+func fits(src reflect.Value, t reflect.Type) bool {
+	dst := reflect.New(t).Elem()
+	switch {
+	case isIntKind(src.Kind()):
+		n := src.Int()
+		switch {
+		case isIntKind(t.Kind()):
+			return !dst.OverflowInt(n)
+		case isUintKind(t.Kind()):
+			return n >= 0 && !dst.OverflowUint(uint64(n))
+		}
+	case isUintKind(src.Kind()):
+		n := src.Uint()
+		switch {
+		case isIntKind(t.Kind()):
+			return n <= math.MaxInt64 && !dst.OverflowInt(int64(n))
+		case isUintKind(t.Kind()):
+			return !dst.OverflowUint(n)
+		}
+	case src.Kind() == reflect.Float32 || src.Kind() == reflect.Float64:
+		f := src.Float()
+		switch {
+		case t.Kind() == reflect.Float32 || t.Kind() == reflect.Float64:
+			return !dst.OverflowFloat(f)
+		case isIntKind(t.Kind()):
+			return f == math.Trunc(f) && f >= math.MinInt64 && f < math.MaxInt64 && !dst.OverflowInt(int64(f))
+		case isUintKind(t.Kind()):
+			return f == math.Trunc(f) && f >= 0 && f < math.MaxUint64 && !dst.OverflowUint(uint64(f))
+		}
+	}
+	return true
+}
+
+func isIntKind(k reflect.Kind) bool {
+	return k >= reflect.Int && k <= reflect.Int64
+}
+
+func isUintKind(k reflect.Kind) bool {
+	return k >= reflect.Uint && k <= reflect.Uintptr
+}
+
+func convertible(src, dst reflect.Kind) bool {
+	isNumber := func(k reflect.Kind) bool {
+		return k >= reflect.Int && k <= reflect.Float64
+	}
+	switch {
+	case isNumber(src) && isNumber(dst):
+		return true
+	case src == reflect.String && dst == reflect.String:
+		return true
+	case src == reflect.Bool && dst == reflect.Bool:
+		return true
+	}
+	return false
+}
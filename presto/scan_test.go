@@ -0,0 +1,171 @@
+// Copyright (c) Facebook, Inc. and its affiliates. All Rights Reserved
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package presto
+
+import (
+	"container/list"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/timescale/presto-go-client/presto/types"
+)
+
+type scanPoint struct {
+	X int64
+	Y int64
+}
+
+type scanBase struct {
+	ID int64
+}
+
+type scanItem struct {
+	scanBase
+	Label  string `presto:"name"`
+	Tags   []string
+	Point  scanPoint
+	Attrs  map[string]int64
+	Note   *string
+	Ignore string `presto:"-"`
+}
+
+var scanResult = fakeResult{
+	Columns: []string{"id", "name", "tags", "point", "attrs", "note", "ignore", "extra"},
+	Types:   []string{"bigint", "varchar", "array(varchar)", "row(x bigint, y bigint)", "map(varchar, bigint)", "varchar", "varchar", "bigint"},
+	Pages: [][]queryData{{
+		{json.Number("1"), "a", []interface{}{"t1", "t2"}, []interface{}{json.Number("1"), json.Number("2")}, map[string]interface{}{"k": json.Number("3")}, "n", "i", json.Number("0")},
+		{json.Number("2"), "b", nil, nil, nil, nil, nil, nil},
+	}},
+}
+
+func scanDB(t *testing.T) *sql.DB {
+	s := newFakeServer(t)
+	s.result = func(string, http.Header) fakeResult { return scanResult }
+	db := s.open(t, "")
+	return db
+}
+
+func TestScanStruct(t *testing.T) {
+	db := scanDB(t)
+	rows, err := db.Query("SELECT * FROM t")
+	require.NoError(t, err)
+	defer rows.Close()
+	var items []scanItem
+	for rows.Next() {
+		var item scanItem
+		require.NoError(t, ScanStruct(rows, &item))
+		items = append(items, item)
+	}
+	require.NoError(t, rows.Err())
+
+	note := "n"
+	assert.Equal(t, []scanItem{
+		{scanBase: scanBase{ID: 1}, Label: "a", Tags: []string{"t1", "t2"}, Point: scanPoint{1, 2}, Attrs: map[string]int64{"k": 3}, Note: &note},
+		{scanBase: scanBase{ID: 2}, Label: "b"},
+	}, items)
+
+	var item scanItem
+	assert.Error(t, ScanStruct(rows, item), "the destination must be a pointer")
+}
+
+func TestQueryStructs(t *testing.T) {
+	db := scanDB(t)
+	items, err := QueryStructs[scanItem](context.Background(), db, "SELECT * FROM t")
+	require.NoError(t, err)
+	require.Len(t, items, 2)
+	assert.Equal(t, "b", items[1].Label)
+
+	_, err = QueryStructs[int](context.Background(), db, "SELECT * FROM t")
+	assert.Error(t, err)
+}
+
+func TestStructScannerCache(t *testing.T) {
+	db := scanDB(t)
+	scanners := make([]*structScanner, 2)
+	for i := range scanners {
+		rows, err := db.Query("SELECT * FROM t")
+		require.NoError(t, err)
+		scanners[i], err = newStructScanner(rows, reflect.TypeOf(scanItem{}))
+		require.NoError(t, err)
+		rows.Close()
+	}
+	assert.Same(t, scanners[0], scanners[1])
+	assert.Equal(t, []int{1}, scanners[0].fields[1])
+	assert.Nil(t, scanners[0].fields[6], "fields tagged with - are skipped")
+	assert.Nil(t, scanners[0].fields[7], "columns without fields are skipped")
+}
+
+func TestStructScannerCacheBound(t *testing.T) {
+	c := &structScannerCache{entries: make(map[structScannerKey]*list.Element), lru: list.New()}
+	first := structScannerKey{t: reflect.TypeOf(scanItem{}), columns: "c0"}
+	c.put(first, &structScanner{})
+	for i := 1; i <= maxStructScanners; i++ {
+		c.put(structScannerKey{t: reflect.TypeOf(scanItem{}), columns: fmt.Sprint("c", i)}, &structScanner{})
+		if i == maxStructScanners/2 {
+			require.NotNil(t, c.get(first), "using an entry keeps it")
+		}
+	}
+	assert.Equal(t, maxStructScanners, c.lru.Len())
+	assert.Len(t, c.entries, maxStructScanners)
+	assert.NotNil(t, c.get(first))
+	assert.Nil(t, c.get(structScannerKey{t: reflect.TypeOf(scanItem{}), columns: "c1"}), "the least recently used entry is evicted")
+}
+
+func TestAssignNumber(t *testing.T) {
+	sig, err := types.Parse("bigint")
+	require.NoError(t, err)
+	for _, tt := range []struct {
+		v    interface{}
+		dst  interface{}
+		want interface{}
+	}{
+		{int64(127), new(int8), int8(127)},
+		{int64(128), new(int8), nil},
+		{int64(-129), new(int8), nil},
+		{int64(1 << 40), new(int32), nil},
+		{int64(65535), new(uint16), uint16(65535)},
+		{int64(65536), new(uint16), nil},
+		{int64(-1), new(uint64), nil},
+		{uint64(math.MaxUint64), new(int64), nil},
+		{uint64(math.MaxUint64), new(uint64), uint64(math.MaxUint64)},
+		{float64(1.5), new(float32), float32(1.5)},
+		{float64(math.MaxFloat64), new(float32), nil},
+		{float64(3), new(int16), int16(3)},
+		{float64(3.5), new(int16), nil},
+		{float64(-1), new(uint), nil},
+		{float64(1e20), new(int64), nil},
+		{int64(1 << 53), new(float64), float64(1 << 53)},
+	} {
+		dst := reflect.ValueOf(tt.dst).Elem()
+		err := assignValue(dst, sig, tt.v)
+		if tt.want == nil {
+			assert.Error(t, err, "%v (%T) to %s", tt.v, tt.v, dst.Type())
+			assert.Zero(t, dst.Interface(), "the destination is left unchanged")
+			continue
+		}
+		if assert.NoError(t, err, "%v (%T) to %s", tt.v, tt.v, dst.Type()) {
+			assert.Equal(t, tt.want, dst.Interface())
+		}
+	}
+}
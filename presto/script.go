@@ -0,0 +1,198 @@
+// Copyright (c) Facebook, Inc. and its affiliates. All Rights Reserved
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package presto
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// ScriptStatement is a statement of a script.
+type ScriptStatement struct {
+	Index  int    // Position of the statement in the script, starting at 0
+	Text   string // Text of the statement, without the terminating semicolon
+	Line   int    // Line of the start of the statement in the script, starting at 1
+	Column int    // Column of the start of the statement in the script, starting at 1
+}
+
+// ScriptResult is the result of a statement run by ExecScript.
+type ScriptResult struct {
+	ScriptStatement
+	Result *Result
+}
+
+// ScriptError reports the statement of a script that failed.
+type ScriptError struct {
+	ScriptStatement
+	Err error
+}
+
+// Error implements the error interface.
+func (e *ScriptError) Error() string {
+	return fmt.Sprintf("presto: statement %d at line %d, column %d: %v", e.Index+1, e.Line, e.Column, e.Err)
+}
+
+// Unwrap implements the unwrap interface.
+func (e *ScriptError) Unwrap() error {
+	return e.Err
+}
+
+// SplitScript splits a script into statements separated by semicolons.
+//
+// Semicolons inside string literals, quoted identifiers and comments, and
+// inside the BEGIN ... END blocks of the body of a CREATE FUNCTION statement
+// or of a WITH FUNCTION query, don't end a statement. Statements made only of
+// comments are skipped.
+func SplitScript(script string) ([]ScriptStatement, error) {
+	tokens, err := lexSQL(script)
+	if err != nil {
+		if syntaxErr, ok := err.(*sqlSyntaxError); ok {
+			line, col := lineColumn(script, syntaxErr.pos)
+			return nil, fmt.Errorf("presto: %s at line %d, column %d", syntaxErr.msg, line, col)
+		}
+		return nil, err
+	}
+	var statements []ScriptStatement
+	depth := 0
+	first, last := -1, -1
+	var leading []string // First words of the statement, upper-cased
+	var prevWord string
+	flush := func() {
+		if first >= 0 {
+			start, end := tokens[first].pos, tokens[last].pos+len(tokens[last].text)
+			line, col := lineColumn(script, start)
+			statements = append(statements, ScriptStatement{
+				Index:  len(statements),
+				Text:   script[start:end],
+				Line:   line,
+				Column: col,
+			})
+		}
+		first, last = -1, -1
+		leading = leading[:0]
+		prevWord = ""
+	}
+	for i, t := range tokens {
+		switch t.kind {
+		case sqlSpace, sqlComment:
+			continue
+		case sqlSymbol:
+			if t.text == ";" && depth == 0 {
+				flush()
+				continue
+			}
+		case sqlWord:
+			word := strings.ToUpper(t.text)
+			if len(leading) < 5 {
+				leading = append(leading, word)
+			}
+			if !isRoutineStatement(leading) {
+				break
+			}
+			afterEnd := prevWord == "END"
+			prevWord = word
+			switch word {
+			case "BEGIN", "CASE":
+				// The CASE of END CASE closes a block.
+				if !afterEnd {
+					depth++
+				}
+			case "END":
+				// END IF, END LOOP, ... close blocks that were not counted.
+				switch strings.ToUpper(nextWord(tokens[i+1:])) {
+				case "IF", "LOOP", "WHILE", "REPEAT", "FOR":
+				default:
+					if depth > 0 {
+						depth--
+					}
+				}
+			}
+		}
+		if first < 0 {
+			first = i
+		}
+		last = i
+	}
+	flush()
+	return statements, nil
+}
+
+// isRoutineStatement reports whether a statement starting with words defines
+// a SQL routine, whose body may contain semicolons.
+func isRoutineStatement(words []string) bool {
+	if len(words) >= 2 && words[0] == "WITH" && words[1] == "FUNCTION" {
+		return true
+	}
+	if len(words) < 2 || words[0] != "CREATE" {
+		return false
+	}
+	words = words[1:]
+	if len(words) >= 2 && words[0] == "OR" && words[1] == "REPLACE" {
+		words = words[2:]
+	}
+	if len(words) >= 1 && words[0] == "TEMPORARY" {
+		words = words[1:]
+	}
+	return len(words) >= 1 && words[0] == "FUNCTION"
+}
+
+// nextWord returns the text of the first word token, skipping spaces and
+// comments.
+func nextWord(tokens []sqlToken) string {
+	for _, t := range tokens {
+		switch t.kind {
+		case sqlSpace, sqlComment:
+			continue
+		case sqlWord:
+			return t.text
+		}
+		return ""
+	}
+	return ""
+}
+
+func lineColumn(text string, pos int) (line, column int) {
+	line = 1 + strings.Count(text[:pos], "\n")
+	return line, pos - strings.LastIndex(text[:pos], "\n")
+}
+
+// ExecScript runs the statements of a script, split by SplitScript, one
+// after the other on conn, so that USE and SET SESSION statements apply to the
+// statements that follow them, and to the statements run on conn afterwards:
+//
+//	conn, err := db.Conn(ctx)
+//	...
+//	defer conn.Close()
+//	results, err := presto.ExecScript(ctx, conn, "USE hive.s; CREATE TABLE t (x int)")
+//
+// It stops at the first statement that fails, and returns the results of the
+// statements that ran before it along with a *ScriptError.
+func ExecScript(ctx context.Context, conn *sql.Conn, script string) ([]ScriptResult, error) {
+	statements, err := SplitScript(script)
+	if err != nil {
+		return nil, err
+	}
+	results := make([]ScriptResult, 0, len(statements))
+	for _, st := range statements {
+		res, err := Exec(ctx, conn, st.Text)
+		if err != nil {
+			return results, &ScriptError{ScriptStatement: st, Err: err}
+		}
+		results = append(results, ScriptResult{ScriptStatement: st, Result: res})
+	}
+	return results, nil
+}
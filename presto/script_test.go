@@ -0,0 +1,119 @@
+// Copyright (c) Facebook, Inc. and its affiliates. All Rights Reserved
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package presto
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitScript(t *testing.T) {
+	for _, tt := range []struct {
+		name   string
+		script string
+		want   []string
+	}{
+		{"single", "SELECT 1", []string{"SELECT 1"}},
+		{"several", "SELECT 1;\nSELECT 2;", []string{"SELECT 1", "SELECT 2"}},
+		{"empty statements", ";; SELECT 1 ;;", []string{"SELECT 1"}},
+		{"comment only", "SELECT 1; -- done\n/* really */", []string{"SELECT 1"}},
+		{"quoted semicolons", `SELECT ';', ";" -- ;` + "\n; SELECT 2", []string{`SELECT ';', ";"`, "SELECT 2"}},
+		{"case expression", "SELECT CASE WHEN x THEN 1 END FROM t; SELECT 2", []string{"SELECT CASE WHEN x THEN 1 END FROM t", "SELECT 2"}},
+		{"begin identifier", "SELECT begin FROM t; SELECT 2", []string{"SELECT begin FROM t", "SELECT 2"}},
+		{"unmatched begin", "BEGIN; INSERT INTO t VALUES 1; COMMIT", []string{"BEGIN", "INSERT INTO t VALUES 1", "COMMIT"}},
+		{"start transaction", "START TRANSACTION; SELECT 1; COMMIT", []string{"START TRANSACTION", "SELECT 1", "COMMIT"}},
+		{
+			"function",
+			"CREATE FUNCTION f(x int) RETURNS int BEGIN DECLARE y int; SET y = x; RETURN y; END; SELECT f(1)",
+			[]string{"CREATE FUNCTION f(x int) RETURNS int BEGIN DECLARE y int; SET y = x; RETURN y; END", "SELECT f(1)"},
+		},
+		{
+			"replaced function with nested blocks",
+			"create or replace temporary function f(x int) returns int begin if x > 0 then begin return 1; end; end if; " +
+				"case x when 0 then return 0; end case; return -1; end; select 1",
+			[]string{
+				"create or replace temporary function f(x int) returns int begin if x > 0 then begin return 1; end; end if; " +
+					"case x when 0 then return 0; end case; return -1; end",
+				"select 1",
+			},
+		},
+		{"function without block", "CREATE FUNCTION f() RETURNS int RETURN 1; SELECT 2", []string{"CREATE FUNCTION f() RETURNS int RETURN 1", "SELECT 2"}},
+		{
+			"inline function",
+			"WITH FUNCTION f() RETURNS int BEGIN RETURN 1; END SELECT f(); SELECT 2",
+			[]string{"WITH FUNCTION f() RETURNS int BEGIN RETURN 1; END SELECT f()", "SELECT 2"},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			statements, err := SplitScript(tt.script)
+			require.NoError(t, err)
+			var got []string
+			for i, st := range statements {
+				assert.Equal(t, i, st.Index)
+				got = append(got, st.Text)
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestSplitScriptPositions(t *testing.T) {
+	statements, err := SplitScript("SELECT 1;\n  -- comment\n  SELECT 2;")
+	require.NoError(t, err)
+	require.Len(t, statements, 2)
+	assert.Equal(t, ScriptStatement{Index: 0, Text: "SELECT 1", Line: 1, Column: 1}, statements[0])
+	assert.Equal(t, ScriptStatement{Index: 1, Text: "SELECT 2", Line: 3, Column: 3}, statements[1])
+
+	_, err = SplitScript("SELECT 1;\nSELECT 'a")
+	assert.EqualError(t, err, "presto: unterminated string literal at line 2, column 8")
+}
+
+func TestExecScript(t *testing.T) {
+	s := newFakeServer(t)
+	s.result = func(query string, _ http.Header) fakeResult {
+		switch query {
+		case "USE s":
+			return fakeResult{Header: http.Header{prestoSetSchemaHeader: {"s"}}}
+		case "SELECT fail":
+			return fakeResult{Columns: []string{"x"}, Error: &stmtError{Message: "failed", ErrorName: "GENERIC_USER_ERROR"}}
+		}
+		return fakeResult{}
+	}
+	db := s.open(t, "")
+	conn, err := db.Conn(context.Background())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	results, err := ExecScript(context.Background(), conn, "USE s;\nCREATE TABLE t (x int);\nSELECT fail;\nDROP TABLE t")
+	var scriptErr *ScriptError
+	require.ErrorAs(t, err, &scriptErr)
+	assert.Equal(t, 2, scriptErr.Index)
+	assert.Equal(t, 3, scriptErr.Line)
+	assert.Len(t, results, 2)
+
+	posts := s.received("POST", "/v1/statement")
+	require.Len(t, posts, 3, "statements after the failing one must not run")
+	assert.Equal(t, "s", posts[1].Header.Get(prestoSchemaHeader), "statements must run on the same connection")
+
+	_, err = conn.ExecContext(context.Background(), "DROP TABLE t")
+	require.NoError(t, err)
+	posts = s.received("POST", "/v1/statement")
+	require.Len(t, posts, 4)
+	assert.Equal(t, "s", posts[3].Header.Get(prestoSchemaHeader), "the session state must be kept on the connection")
+}
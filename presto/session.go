@@ -0,0 +1,113 @@
+// Copyright (c) Facebook, Inc. and its affiliates. All Rights Reserved
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package presto
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// sessionState holds the request headers describing the session of a
+// connection. It is updated by the goroutines fetching query results and
+// read whenever a request is built, so all accesses are guarded by a mutex
+// and readers only ever get a copy of the headers.
+type sessionState struct {
+	mu      sync.Mutex
+	headers http.Header
+	invalid bool
+}
+
+func newSessionState(headers http.Header) *sessionState {
+	return &sessionState{headers: headers.Clone()}
+}
+
+// snapshot returns a copy of the current session headers.
+func (s *sessionState) snapshot() http.Header {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.headers.Clone()
+}
+
+// values returns a copy of the values of a session header.
+func (s *sessionState) values(key string) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.headers.Values(key)...)
+}
+
+// reset replaces the session headers with a copy of headers.
+func (s *sessionState) reset(headers http.Header) {
+	s.mu.Lock()
+	s.headers = headers.Clone()
+	s.mu.Unlock()
+}
+
+// dropPreparedStatements forgets the prepared statements of the session.
+func (s *sessionState) dropPreparedStatements() {
+	s.mu.Lock()
+	s.headers.Del(preparedStatementHeader)
+	s.mu.Unlock()
+}
+
+// isInvalid reports whether the server sent a session state change that
+// could not be tracked.
+func (s *sessionState) isInvalid() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.invalid
+}
+
+// update applies the session state changes found in the headers of a
+// successful response in a single step, so concurrent requests never observe
+// a partially applied change.
+func (s *sessionState) update(resp http.Header) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for src, dst := range responseToRequestHeaderMap {
+		if v := resp.Get(src); v != "" {
+			s.headers.Set(dst, v)
+		}
+	}
+	if v := resp.Get(prestoAddedPrepareHeader); v != "" {
+		s.headers.Add(preparedStatementHeader, v)
+	}
+	if v := resp.Get(prestoDeallocatedPrepareHeader); v != "" {
+		s.removePrefixed(preparedStatementHeader, v+"=")
+	}
+	if v := resp.Get(prestoSetSessionHeader); v != "" {
+		s.headers.Add(prestoSessionHeader, v)
+	}
+	if v := resp.Get(prestoClearSessionHeader); v != "" {
+		s.removePrefixed(prestoSessionHeader, v+"=")
+	}
+	for _, name := range unsupportedResponseHeaders {
+		if v := resp.Get(name); v != "" {
+			s.invalid = true
+			return ErrUnsupportedHeader
+		}
+	}
+	return nil
+}
+
+func (s *sessionState) removePrefixed(key, prefix string) {
+	values := s.headers.Values(key)
+	s.headers.Del(key)
+	for _, v := range values {
+		if !strings.HasPrefix(v, prefix) {
+			s.headers.Add(key, v)
+		}
+	}
+}
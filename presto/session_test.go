@@ -0,0 +1,130 @@
+// Copyright (c) Facebook, Inc. and its affiliates. All Rights Reserved
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package presto
+
+import (
+	"context"
+	"database/sql/driver"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSessionReset(t *testing.T) {
+	for _, tt := range []struct {
+		name       string
+		dsn        string
+		wantSchema string
+	}{
+		{"reset", "schema=dsn", "dsn"},
+		{"kept", "schema=dsn&keep_session_state=true", "changed"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newFakeServer(t)
+			s.result = func(query string, _ http.Header) fakeResult {
+				if query == "USE changed" {
+					return fakeResult{Header: http.Header{prestoSetSchemaHeader: {"changed"}}}
+				}
+				return fakeRows
+			}
+			db := s.open(t, tt.dsn)
+			db.SetMaxOpenConns(1)
+
+			_, err := db.Exec("USE changed")
+			require.NoError(t, err)
+			_, err = db.Exec("SELECT 1")
+			require.NoError(t, err)
+
+			posts := s.received("POST", "/v1/statement")
+			require.Len(t, posts, 2)
+			assert.Equal(t, tt.wantSchema, posts[1].Header.Get(prestoSchemaHeader))
+		})
+	}
+}
+
+func TestConnCloseDropsPreparedStatements(t *testing.T) {
+	s := newFakeServer(t)
+	c, err := newConn(s.dsn(""))
+	require.NoError(t, err)
+	require.NoError(t, c.session.update(http.Header{prestoAddedPrepareHeader: {"stmt=SELECT+1"}}))
+	require.Equal(t, []string{"stmt=SELECT+1"}, c.session.values(preparedStatementHeader))
+
+	require.NoError(t, c.Close())
+	assert.False(t, c.IsValid())
+	assert.Empty(t, c.session.values(preparedStatementHeader))
+	assert.Empty(t, s.received("POST", "/"), "closing a connection must not send requests")
+}
+
+// TestConnConcurrentUse is meant to be run with the race detector.
+func TestConnConcurrentUse(t *testing.T) {
+	s := newFakeServer(t)
+	s.result = func(query string, _ http.Header) fakeResult {
+		if strings.HasPrefix(query, "SET SESSION ") {
+			return fakeResult{Header: http.Header{prestoSetSessionHeader: {strings.TrimPrefix(query, "SET SESSION ")}}}
+		}
+		return fakeResult{Columns: []string{"x"}, Pages: [][]queryData{{{1}, {2}}, {{3}}}}
+	}
+	c, err := newConn(s.dsn(""))
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			st := &driverStmt{conn: c, query: "SELECT x"}
+			defer st.Close()
+			rows, err := st.QueryContext(ctx, nil)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			defer rows.Close()
+			dest := make([]driver.Value, 1)
+			for rows.Next(dest) == nil {
+			}
+		}()
+		go func(i int) {
+			defer wg.Done()
+			st := &driverStmt{conn: c, query: "SET SESSION p" + strconv.Itoa(i) + "=1"}
+			defer st.Close()
+			if _, err := st.ExecContext(ctx, nil); err != nil {
+				t.Error(err)
+			}
+		}(i)
+	}
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			c.IsValid()
+			c.session.snapshot()
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		c.Close()
+	}()
+	wg.Wait()
+
+	assert.False(t, c.IsValid())
+	assert.Len(t, c.session.values(prestoSessionHeader), 8)
+}
@@ -0,0 +1,174 @@
+// Copyright (c) Facebook, Inc. and its affiliates. All Rights Reserved
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package presto
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// ErrIdleTimeout indicates that a query was cancelled because its rows were
+// not read for longer than the idle_timeout set in the DSN.
+var ErrIdleTimeout = errors.New("presto: query cancelled after idle timeout")
+
+// timeouts are the timeouts of a connection, set in the DSN:
+//
+//	submit_timeout  timeout of the request submitting a statement
+//	poll_timeout    timeout of each request polling the next URI of a query
+//	query_timeout   total wall time of a query, also sent to the server as the query_max_run_time session property
+//	idle_timeout    time the rows of a query may be left unread before the query is cancelled
+//	cancel_timeout  timeout of the request cancelling a query
+//
+// Unless set, the timeout of a request is the deadline of its context, or
+// the query timeout if set, else DefaultQueryTimeout, and the timeout to
+// cancel a query is DefaultCancelQueryTimeout.
+type timeouts struct {
+	request time.Duration // Timeout of requests without a context deadline or a timeout of their own
+	submit  time.Duration
+	poll    time.Duration
+	query   time.Duration
+	idle    time.Duration
+	cancel  time.Duration
+}
+
+func parseTimeouts(query url.Values) (timeouts, error) {
+	t := timeouts{
+		request: DefaultQueryTimeout,
+		cancel:  DefaultCancelQueryTimeout,
+	}
+	for _, d := range []struct {
+		name string
+		dst  *time.Duration
+	}{
+		{"submit_timeout", &t.submit},
+		{"poll_timeout", &t.poll},
+		{"query_timeout", &t.query},
+		{"idle_timeout", &t.idle},
+		{"cancel_timeout", &t.cancel},
+	} {
+		v := query.Get(d.name)
+		if v == "" {
+			continue
+		}
+		timeout, err := time.ParseDuration(v)
+		if err != nil || timeout <= 0 {
+			return t, fmt.Errorf("presto: malformed %s %q", d.name, v)
+		}
+		*d.dst = timeout
+	}
+	if t.query > 0 {
+		// The query timeout bounds the requests of a query, e.g. a long
+		// poll of a slow query isn't cut short by DefaultQueryTimeout.
+		t.request = t.query
+	}
+	return t, nil
+}
+
+// sessionProperties returns the session properties of the DSN, with the
+// query timeout as query_max_run_time unless it is set explicitly.
+func (t timeouts) sessionProperties(props string) string {
+	if t.query == 0 {
+		return props
+	}
+	for _, kv := range strings.Split(props, ",") {
+		if strings.HasPrefix(strings.TrimSpace(kv), "query_max_run_time=") {
+			return props
+		}
+	}
+	prop := "query_max_run_time=" + strconv.FormatInt(t.query.Milliseconds(), 10) + "ms"
+	if props == "" {
+		return prop
+	}
+	return props + "," + prop
+}
+
+type requestTimeoutKey struct{}
+
+// withRequestTimeout sets the timeout of the requests sent with ctx, if any.
+func withRequestTimeout(ctx context.Context, timeout time.Duration) context.Context {
+	if timeout == 0 {
+		return ctx
+	}
+	return context.WithValue(ctx, requestTimeoutKey{}, timeout)
+}
+
+// requestTimeout returns the timeout of a request sent with ctx.
+func (c *Conn) requestTimeout(ctx context.Context) time.Duration {
+	timeout, _ := ctx.Value(requestTimeoutKey{}).(time.Duration)
+	if deadline, ok := ctx.Deadline(); ok {
+		if until := time.Until(deadline); timeout == 0 || until < timeout {
+			timeout = until
+		}
+	} else if timeout == 0 {
+		timeout = c.timeouts.request
+	}
+	return timeout
+}
+
+// States of the idle timer of rows.
+const (
+	idleStopped int32 = iota
+	idleArmed
+	idleExpired
+)
+
+// startIdleTimer starts counting the time the rows are left unread.
+func (qr *driverRows) startIdleTimer() {
+	idle := qr.stmt.conn.timeouts.idle
+	if idle == 0 {
+		return
+	}
+	atomic.StoreInt32(&qr.idleState, idleArmed)
+	if qr.idleTimer == nil {
+		qr.idleTimer = time.AfterFunc(idle, qr.expireIdle)
+	} else {
+		qr.idleTimer.Reset(idle)
+	}
+}
+
+// stopIdleTimer stops the idle timer, and reports whether it expired.
+func (qr *driverRows) stopIdleTimer() bool {
+	if qr.idleTimer == nil {
+		return false
+	}
+	qr.idleTimer.Stop()
+	return !atomic.CompareAndSwapInt32(&qr.idleState, idleArmed, idleStopped) &&
+		atomic.LoadInt32(&qr.idleState) == idleExpired
+}
+
+// expireIdle cancels the query once its rows were left unread for longer
+// than the idle timeout.
+func (qr *driverRows) expireIdle() {
+	if !atomic.CompareAndSwapInt32(&qr.idleState, idleArmed, idleExpired) {
+		return
+	}
+	conn := qr.stmt.conn
+	conn.debug(context.Background(), "presto: cancelling idle query", "query_id", qr.queryID, "idle_timeout", conn.timeouts.idle)
+	qr.stmt.run.stop()
+	if qr.queryID != "" {
+		ctx, cancel := context.WithTimeout(context.Background(), conn.timeouts.cancel)
+		defer cancel()
+		if err := conn.cancelQuery(qr.stmt.run.context(ctx), qr.stmt.baseURL, qr.queryID, qr.stmt.user); err != nil {
+			conn.debug(ctx, "presto: cancelling idle query failed", "query_id", qr.queryID, "error", err)
+		}
+	}
+	qr.finish(ErrIdleTimeout, false)
+}
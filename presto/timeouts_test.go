@@ -0,0 +1,179 @@
+// Copyright (c) Facebook, Inc. and its affiliates. All Rights Reserved
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package presto
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseTimeouts(t *testing.T) {
+	for _, tt := range []struct {
+		query string
+		want  timeouts
+	}{
+		{"", timeouts{request: DefaultQueryTimeout, cancel: DefaultCancelQueryTimeout}},
+		{
+			"submit_timeout=1s&poll_timeout=2s&idle_timeout=3s&cancel_timeout=4s",
+			timeouts{request: DefaultQueryTimeout, submit: time.Second, poll: 2 * time.Second, idle: 3 * time.Second, cancel: 4 * time.Second},
+		},
+		{"query_timeout=5m", timeouts{request: 5 * time.Minute, query: 5 * time.Minute, cancel: DefaultCancelQueryTimeout}},
+	} {
+		t.Run(tt.query, func(t *testing.T) {
+			query, err := url.ParseQuery(tt.query)
+			require.NoError(t, err)
+			got, err := parseTimeouts(query)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+	for _, query := range []string{"submit_timeout=1", "poll_timeout=x", "query_timeout=0s", "idle_timeout=-1s"} {
+		q, err := url.ParseQuery(query)
+		require.NoError(t, err)
+		_, err = parseTimeouts(q)
+		assert.Error(t, err, query)
+	}
+}
+
+func TestSessionProperties(t *testing.T) {
+	for _, tt := range []struct {
+		query time.Duration
+		props string
+		want  string
+	}{
+		{0, "", ""},
+		{0, "a=1", "a=1"},
+		{90 * time.Second, "", "query_max_run_time=90000ms"},
+		{time.Minute, "a=1", "a=1,query_max_run_time=60000ms"},
+		{time.Minute, "a=1, query_max_run_time=1h", "a=1, query_max_run_time=1h"},
+	} {
+		assert.Equal(t, tt.want, timeouts{query: tt.query}.sessionProperties(tt.props))
+	}
+}
+
+func TestRequestTimeout(t *testing.T) {
+	c := &Conn{timeouts: timeouts{request: time.Minute}}
+	ctx := context.Background()
+	assert.Equal(t, time.Minute, c.requestTimeout(ctx))
+	assert.Equal(t, time.Second, c.requestTimeout(withRequestTimeout(ctx, time.Second)))
+	assert.Equal(t, time.Minute, c.requestTimeout(withRequestTimeout(ctx, 0)))
+
+	deadline, cancel := context.WithTimeout(ctx, time.Hour)
+	defer cancel()
+	assert.InDelta(t, time.Hour, c.requestTimeout(deadline), float64(time.Second))
+	assert.Equal(t, time.Second, c.requestTimeout(withRequestTimeout(deadline, time.Second)))
+	short, cancel := context.WithTimeout(ctx, time.Second)
+	defer cancel()
+	assert.LessOrEqual(t, c.requestTimeout(withRequestTimeout(short, time.Hour)), time.Second)
+}
+
+// slowPages delays the responses with the pages of the results of s.
+func slowPages(s *fakeServer, delay time.Duration) {
+	s.handler = func(w http.ResponseWriter, r *http.Request) bool {
+		if r.Method == "GET" && strings.HasPrefix(r.URL.Path, "/v1/statement/") {
+			select {
+			case <-time.After(delay):
+			case <-r.Context().Done():
+				return true
+			}
+		}
+		return false
+	}
+}
+
+func TestTimeouts(t *testing.T) {
+	s := newFakeServer(t)
+	slowPages(s, 200*time.Millisecond)
+
+	for _, tt := range []struct {
+		query   string
+		wantErr bool
+	}{
+		{"", false},
+		{"poll_timeout=50ms", true},
+		{"query_timeout=50ms", true},
+		{"query_timeout=1s", false},
+	} {
+		t.Run(tt.query, func(t *testing.T) {
+			db := s.open(t, ""+tt.query)
+
+			var n int
+			start := time.Now()
+			err := db.QueryRow("SELECT 1").Scan(&n)
+			if !tt.wantErr {
+				require.NoError(t, err)
+				assert.Equal(t, 1, n)
+				return
+			}
+			assert.Error(t, err)
+			assert.Less(t, time.Since(start), 200*time.Millisecond)
+		})
+	}
+}
+
+func TestQueryTimeoutRequests(t *testing.T) {
+	defer func(d time.Duration) { DefaultQueryTimeout = d }(DefaultQueryTimeout)
+	DefaultQueryTimeout = 50 * time.Millisecond
+	s := newFakeServer(t)
+	slowPages(s, 200*time.Millisecond)
+
+	// Without a context deadline, the requests are cut short by
+	// DefaultQueryTimeout, unless the query has a timeout.
+	for _, tt := range []struct {
+		query   string
+		wantErr bool
+	}{
+		{"", true},
+		{"query_timeout=1s", false},
+		{"poll_timeout=1s", false},
+	} {
+		t.Run(tt.query, func(t *testing.T) {
+			db := s.open(t, ""+tt.query)
+
+			var n int
+			err := db.QueryRow("SELECT 1").Scan(&n)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestIdleTimeout(t *testing.T) {
+	s := newFakeServer(t)
+	s.result = func(query string, header http.Header) fakeResult {
+		return fakeResult{Columns: []string{"_col0"}, Pages: [][]queryData{{{1}}, {{2}}}}
+	}
+	db := s.open(t, "idle_timeout=50ms")
+
+	rows, err := db.Query("SELECT 1")
+	require.NoError(t, err)
+	defer rows.Close()
+	require.True(t, rows.Next())
+	time.Sleep(200 * time.Millisecond)
+	for rows.Next() {
+	}
+	assert.ErrorIs(t, rows.Err(), ErrIdleTimeout)
+	assert.NotEmpty(t, s.received("DELETE", "/v1/query/"))
+}
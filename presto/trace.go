@@ -0,0 +1,178 @@
+// Copyright (c) Facebook, Inc. and its affiliates. All Rights Reserved
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package presto
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Tracer traces the queries run by the connections of a Connector, e.g. to
+// export them as OpenTelemetry spans with the otelpresto package.
+type Tracer interface {
+	// StartQuery is called before a query is submitted. The requests of the
+	// query are sent with the returned context.
+	StartQuery(ctx context.Context, query string) (context.Context, QueryTrace)
+}
+
+// QueryTrace traces a single query.
+type QueryTrace interface {
+	// StartRequest is called before every HTTP request sent for the query:
+	// the submission, each poll of the next URI and the cancellation. It may
+	// add headers to the request, e.g. to propagate the trace context.
+	StartRequest(ctx context.Context, req *http.Request) RequestTrace
+	// Update is called with every response of the query.
+	Update(QueryProgressInfo)
+	// Finish is called once, when the query is done.
+	Finish(QueryDone)
+}
+
+// RequestTrace traces a single HTTP request.
+type RequestTrace interface {
+	// Retry is called when the server is busy, before waiting for delay to
+	// send the request again.
+	Retry(statusCode int, delay time.Duration)
+	// End is called with the status code of the response, which is 0 if the
+	// request failed without a response, and the error of the request.
+	End(statusCode int, err error)
+}
+
+// QueryDone describes a query when it is done.
+type QueryDone struct {
+	QueryId    string
+	Rows       int64 // Number of rows read by the client
+	QueryStats stmtStats
+	Err        error // Error of the query, nil if it succeeded or was cancelled by closing the rows
+	Cancelled  bool  // The rows were closed before reading all the results
+}
+
+// queryRun holds the tracing, metrics, logging and audit hooks of a single
+// run of a statement, and its slot when the number of running queries is
+// limited.
+type queryRun struct {
+	conn    *Conn
+	trace   QueryTrace
+	metrics Metrics
+	state   string
+	start   time.Time
+	audit   AuditRecord
+	limiter *queryLimiter      // Limiter to release once done, if a slot was acquired
+	stop    context.CancelFunc // Cancels the requests of the query, and stops its query timeout
+	once    sync.Once
+}
+
+type queryTraceKey struct{}
+
+// startQuery starts a run of the statement and returns the context to send
+// its requests with.
+func (st *driverStmt) startQuery(ctx context.Context) context.Context {
+	st.run = &queryRun{conn: st.conn, metrics: st.conn.metrics, start: time.Now()}
+	if st.conn.timeouts.query > 0 {
+		ctx, st.run.stop = context.WithTimeout(ctx, st.conn.timeouts.query)
+	} else {
+		ctx, st.run.stop = context.WithCancel(ctx)
+	}
+	if st.run.metrics != nil {
+		st.run.metrics.QueryStarted()
+	}
+	if st.conn.tracer != nil {
+		ctx, st.run.trace = st.conn.tracer.StartQuery(ctx, st.query)
+	}
+	return st.run.context(ctx)
+}
+
+// context returns ctx with the hooks used by Conn.roundTrip.
+func (r *queryRun) context(ctx context.Context) context.Context {
+	if r == nil || r.trace == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, queryTraceKey{}, r.trace)
+}
+
+// update reports a response of the query.
+func (r *queryRun) update(queryID string, stats stmtStats) {
+	if r == nil {
+		return
+	}
+	if stats.State != r.state {
+		r.conn.debug(context.Background(), "presto: query state changed", "query_id", queryID, "from", r.state, "to", stats.State)
+		r.state = stats.State
+	}
+	if r.trace == nil {
+		return
+	}
+	r.trace.Update(QueryProgressInfo{QueryId: queryID, QueryStats: stats})
+}
+
+// rowsReturned reports a page of results read by the client.
+func (r *queryRun) rowsReturned(rows int) {
+	if r == nil || r.metrics == nil {
+		return
+	}
+	r.metrics.RowsReturned(rows)
+}
+
+// acquire waits for a slot to run the query, if the number of queries
+// running at once is limited.
+func (r *queryRun) acquire(ctx context.Context, priority QueryPriority) error {
+	if r == nil || r.conn.limiter == nil {
+		return nil
+	}
+	wait, err := r.conn.limiter.acquire(ctx, priority)
+	if m, ok := r.metrics.(QueueMetrics); ok {
+		m.QueryQueued(wait)
+	}
+	if err != nil {
+		return err
+	}
+	r.limiter = r.conn.limiter
+	return nil
+}
+
+// finish reports the end of the query, only the first call has an effect.
+func (r *queryRun) finish(done QueryDone) {
+	if r == nil {
+		return
+	}
+	r.once.Do(func() {
+		if r.limiter != nil {
+			r.limiter.release()
+		}
+		r.conn.debug(context.Background(), "presto: query done", "query_id", done.QueryId, "state", done.QueryStats.State, "rows", done.Rows, "cancelled", done.Cancelled, "error", done.Err)
+		if r.metrics != nil {
+			if done.Cancelled || isCancellation(done.Err) {
+				r.metrics.QueryCancelled()
+			} else if done.Err != nil {
+				r.metrics.QueryFailed(errorName(done.Err))
+			}
+		}
+		if r.trace != nil {
+			r.trace.Finish(done)
+		}
+		r.recordAudit(done)
+		r.stop()
+	})
+}
+
+// startRequest returns the trace of a request sent with ctx, or nil.
+func startRequest(ctx context.Context, req *http.Request) RequestTrace {
+	trace, _ := ctx.Value(queryTraceKey{}).(QueryTrace)
+	if trace == nil {
+		return nil
+	}
+	return trace.StartRequest(ctx, req)
+}
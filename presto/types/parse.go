@@ -0,0 +1,202 @@
+// Copyright (c) Facebook, Inc. and its affiliates. All Rights Reserved
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package types
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// multiWordTypes are the types whose name contains spaces. Any other name
+// made of several words is a row field name followed by its type.
+var multiWordTypes = map[string]bool{
+	"time with time zone":      true,
+	"timestamp with time zone": true,
+	"interval year to month":   true,
+	"interval day to second":   true,
+	"double precision":         true,
+}
+
+// Parse parses the textual form of a type, such as the type of a result
+// column or the data_type of a column in information_schema, e.g.
+// row(a bigint, b array(varchar(10))). Type names are lower-cased, row field
+// names are kept as written, without the quotes of quoted names.
+func Parse(text string) (Signature, error) {
+	p := &parser{text: text}
+	sig, err := p.parseType()
+	if err != nil {
+		return Signature{}, err
+	}
+	p.skipSpaces()
+	if p.pos != len(p.text) {
+		return Signature{}, p.errorf("unexpected %q", p.text[p.pos:])
+	}
+	return sig, nil
+}
+
+type parser struct {
+	text string
+	pos  int
+}
+
+func (p *parser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("invalid type %q at offset %d: %s", p.text, p.pos, fmt.Sprintf(format, args...))
+}
+
+func (p *parser) skipSpaces() {
+	for p.pos < len(p.text) && p.text[p.pos] == ' ' {
+		p.pos++
+	}
+}
+
+func (p *parser) peek() byte {
+	p.skipSpaces()
+	if p.pos == len(p.text) {
+		return 0
+	}
+	return p.text[p.pos]
+}
+
+func isWordByte(c byte) bool {
+	return c == '_' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+// word reads an identifier, or returns an empty string.
+func (p *parser) word() string {
+	p.skipSpaces()
+	start := p.pos
+	for p.pos < len(p.text) && isWordByte(p.text[p.pos]) {
+		p.pos++
+	}
+	return p.text[start:p.pos]
+}
+
+// words reads a sequence of identifiers separated by spaces.
+func (p *parser) words() string {
+	var ws []string
+	for {
+		w := p.word()
+		if w == "" {
+			return strings.Join(ws, " ")
+		}
+		ws = append(ws, w)
+	}
+}
+
+func (p *parser) parseType() (Signature, error) {
+	name := p.words()
+	if name == "" {
+		return Signature{}, p.errorf("type name expected")
+	}
+	sig := Signature{RawType: strings.ToLower(name)}
+	if p.peek() == '(' {
+		p.pos++
+		args, err := p.parseArguments(sig.RawType)
+		if err != nil {
+			return Signature{}, err
+		}
+		sig.Arguments = args
+		// The precision comes before the time zone, as in timestamp(3) with time zone.
+		if suffix := p.words(); suffix != "" {
+			sig.RawType += " " + strings.ToLower(suffix)
+		}
+	}
+	if strings.Contains(sig.RawType, " ") && !multiWordTypes[sig.RawType] {
+		return Signature{}, p.errorf("unknown type %q", sig.RawType)
+	}
+	return sig, nil
+}
+
+func (p *parser) parseArguments(rawType string) ([]Argument, error) {
+	var args []Argument
+	for {
+		arg, err := p.parseArgument(rawType)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, arg)
+		switch p.peek() {
+		case ',':
+			p.pos++
+		case ')':
+			p.pos++
+			return args, nil
+		default:
+			return nil, p.errorf("',' or ')' expected")
+		}
+	}
+}
+
+func (p *parser) parseArgument(rawType string) (Argument, error) {
+	if c := p.peek(); c >= '0' && c <= '9' {
+		n, err := strconv.ParseInt(p.word(), 10, 64)
+		if err != nil {
+			return Argument{}, p.errorf("%s", err)
+		}
+		return Argument{Kind: KindLong, Long: n}, nil
+	}
+	if rawType != "row" {
+		sig, err := p.parseType()
+		if err != nil {
+			return Argument{}, err
+		}
+		return Argument{Kind: KindType, Type: &sig}, nil
+	}
+
+	// Row fields are either a type, or a field name followed by a type.
+	var name string
+	if p.peek() == '"' {
+		quoted, err := p.quotedIdentifier()
+		if err != nil {
+			return Argument{}, err
+		}
+		name = quoted
+	} else {
+		start := p.pos
+		if sig, err := p.parseType(); err == nil {
+			if c := p.peek(); c == ',' || c == ')' {
+				return Argument{Kind: KindNamedType, Type: &sig}, nil
+			}
+		}
+		p.pos = start
+		name = p.word()
+	}
+	sig, err := p.parseType()
+	if err != nil {
+		return Argument{}, err
+	}
+	return Argument{Kind: KindNamedType, Type: &sig, FieldName: name}, nil
+}
+
+func (p *parser) quotedIdentifier() (string, error) {
+	var b strings.Builder
+	p.pos++
+	for p.pos < len(p.text) {
+		c := p.text[p.pos]
+		p.pos++
+		if c != '"' {
+			b.WriteByte(c)
+			continue
+		}
+		if p.pos < len(p.text) && p.text[p.pos] == '"' {
+			b.WriteByte('"')
+			p.pos++
+			continue
+		}
+		return b.String(), nil
+	}
+	return "", p.errorf("unterminated quoted identifier")
+}
@@ -0,0 +1,93 @@
+// Copyright (c) Facebook, Inc. and its affiliates. All Rights Reserved
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package types
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	for _, tt := range []struct {
+		text string
+		want string // Textual form of the parsed type, the text itself if empty
+	}{
+		{text: "bigint"},
+		{text: "VARCHAR", want: "varchar"},
+		{text: "varchar(10)"},
+		{text: "decimal(10,2)", want: "decimal(10, 2)"},
+		{text: "timestamp(3) with time zone"},
+		{text: "time with time zone"},
+		{text: "interval day to second"},
+		{text: "double precision"},
+		{text: "array(array(varchar(5)))"},
+		{text: "map(varchar, array(bigint))"},
+		{text: "row(a bigint, b varchar)"},
+		{text: "row(bigint, varchar)"},
+		{text: "row(x row(y double))"},
+		{text: `row("a b" bigint, "q""uote" varchar)`},
+		{text: `row("x" bigint)`, want: "row(x bigint)"},
+		{text: "row(timestamp timestamp(3))"},
+		{text: "  array( bigint )  ", want: "array(bigint)"},
+	} {
+		t.Run(tt.text, func(t *testing.T) {
+			sig, err := Parse(tt.text)
+			require.NoError(t, err)
+			want := tt.want
+			if want == "" {
+				want = tt.text
+			}
+			assert.Equal(t, want, sig.String())
+
+			again, err := Parse(sig.String())
+			require.NoError(t, err)
+			assert.Equal(t, sig, again, "parsing the textual form must give the same type")
+		})
+	}
+}
+
+func TestParseStructure(t *testing.T) {
+	sig, err := Parse(`row(id bigint, "Tags" array(varchar(3)), m map(varchar, double))`)
+	require.NoError(t, err)
+	assert.Equal(t, "row", sig.RawType)
+	require.Len(t, sig.Arguments, 3)
+	assert.Equal(t, "id", sig.Arguments[0].FieldName)
+	assert.Equal(t, "Tags", sig.Arguments[1].FieldName, "field names keep their case")
+	assert.Equal(t, KindNamedType, sig.Arguments[1].Kind)
+	assert.Equal(t, "array", sig.Elem(1).RawType)
+	assert.Equal(t, Argument{Kind: KindLong, Long: 3}, sig.Elem(1).Elem(0).Arguments[0])
+	assert.Equal(t, "double", sig.Elem(2).Elem(1).RawType)
+	assert.Equal(t, Signature{}, sig.Elem(5))
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, text := range []string{
+		"",
+		"varchar(",
+		"decimal(10 2)",
+		"row(a bigint",
+		"array(bigint))",
+		`row("a bigint)`,
+		"unknown words",
+		"varchar(99999999999999999999)",
+	} {
+		t.Run(text, func(t *testing.T) {
+			_, err := Parse(text)
+			assert.Error(t, err)
+		})
+	}
+}
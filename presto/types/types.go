@@ -0,0 +1,178 @@
+// Copyright (c) Facebook, Inc. and its affiliates. All Rights Reserved
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package types models Presto types as trees of type signatures.
+//
+// Signatures can be decoded from the JSON typeSignature of a result column,
+// as sent by both Presto and Trino, or parsed from the textual form of a type:
+//
+//	sig, err := types.Parse("row(a bigint, b array(varchar(10)))")
+//	sig.RawType                   // "row"
+//	sig.Arguments[1].FieldName    // "b"
+//	sig.Arguments[1].Type.String() // "array(varchar(10))"
+//
+// Formatting a signature with String returns its textual form, which parses
+// back to the same signature.
+package types
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Kind is the kind of a type argument.
+type Kind string
+
+const (
+	KindType      = Kind("TYPE")       // A type, e.g. the element type of an array
+	KindNamedType = Kind("NAMED_TYPE") // A row field, with an optional name
+	KindLong      = Kind("LONG")       // A number, e.g. the length of a varchar
+
+	// Kinds used by Presto in JSON type signatures, decoded as the kinds above.
+	kindTypeSignature      = Kind("TYPE_SIGNATURE")
+	kindNamedTypeSignature = Kind("NAMED_TYPE_SIGNATURE")
+	kindLongLiteral        = Kind("LONG_LITERAL")
+)
+
+// Signature is a type, with its arguments if it's a parametric type.
+type Signature struct {
+	RawType   string // Name of the type, e.g. varchar, decimal, map or timestamp with time zone
+	Arguments []Argument
+}
+
+// Argument is an argument of a parametric type.
+type Argument struct {
+	Kind      Kind
+	Type      *Signature // Set for KindType and KindNamedType
+	FieldName string     // Set for KindNamedType, if the row field has a name
+	Long      int64      // Set for KindLong
+}
+
+// Elem returns the type of the i-th type or row field argument, or the zero
+// Signature if there is none.
+func (s Signature) Elem(i int) Signature {
+	if i >= len(s.Arguments) || s.Arguments[i].Type == nil {
+		return Signature{}
+	}
+	return *s.Arguments[i].Type
+}
+
+// String returns the textual form of the type, e.g. decimal(10, 2).
+func (s Signature) String() string {
+	if len(s.Arguments) == 0 {
+		return s.RawType
+	}
+	args := make([]string, len(s.Arguments))
+	for i, arg := range s.Arguments {
+		args[i] = arg.String()
+	}
+	name, suffix := s.RawType, ""
+	// The precision comes before the time zone, as in timestamp(3) with time zone.
+	if strings.HasSuffix(name, " with time zone") {
+		name, suffix = strings.TrimSuffix(name, " with time zone"), " with time zone"
+	}
+	return name + "(" + strings.Join(args, ", ") + ")" + suffix
+}
+
+// String returns the textual form of the argument.
+func (a Argument) String() string {
+	switch a.Kind {
+	case KindLong:
+		return strconv.FormatInt(a.Long, 10)
+	case KindNamedType:
+		if a.FieldName != "" {
+			return formatFieldName(a.FieldName) + " " + a.Type.String()
+		}
+	}
+	if a.Type == nil {
+		return ""
+	}
+	return a.Type.String()
+}
+
+func formatFieldName(name string) string {
+	for i := 0; i < len(name); i++ {
+		c := name[i]
+		if !isWordByte(c) || i == 0 && c >= '0' && c <= '9' {
+			return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+		}
+	}
+	return name
+}
+
+// UnmarshalJSON decodes a JSON type signature. Nested type signatures are
+// either objects, or strings containing the textual form of the type.
+func (s *Signature) UnmarshalJSON(b []byte) error {
+	if len(b) > 0 && b[0] == '"' {
+		var text string
+		if err := json.Unmarshal(b, &text); err != nil {
+			return err
+		}
+		sig, err := Parse(text)
+		if err != nil {
+			return err
+		}
+		*s = sig
+		return nil
+	}
+	var raw struct {
+		RawType   string `json:"rawType"`
+		Arguments []struct {
+			Kind  Kind            `json:"kind"`
+			Value json.RawMessage `json:"value"`
+		} `json:"arguments"`
+	}
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return err
+	}
+	sig := Signature{RawType: raw.RawType}
+	for _, rawArg := range raw.Arguments {
+		var arg Argument
+		switch rawArg.Kind {
+		case KindType, kindTypeSignature:
+			arg.Kind = KindType
+			arg.Type = new(Signature)
+			if err := json.Unmarshal(rawArg.Value, arg.Type); err != nil {
+				return err
+			}
+		case KindNamedType, kindNamedTypeSignature:
+			var named struct {
+				FieldName *struct {
+					Name string `json:"name"`
+				} `json:"fieldName"`
+				TypeSignature Signature `json:"typeSignature"`
+			}
+			if err := json.Unmarshal(rawArg.Value, &named); err != nil {
+				return err
+			}
+			arg.Kind = KindNamedType
+			arg.Type = &named.TypeSignature
+			if named.FieldName != nil {
+				arg.FieldName = named.FieldName.Name
+			}
+		case KindLong, kindLongLiteral:
+			arg.Kind = KindLong
+			if err := json.Unmarshal(rawArg.Value, &arg.Long); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("unknown argument kind: %s", rawArg.Kind)
+		}
+		sig.Arguments = append(sig.Arguments, arg)
+	}
+	*s = sig
+	return nil
+}
@@ -0,0 +1,53 @@
+// Copyright (c) Facebook, Inc. and its affiliates. All Rights Reserved
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package types
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSignatureUnmarshalJSON(t *testing.T) {
+	want, err := Parse("row(a bigint, array(varchar(10)))")
+	require.NoError(t, err)
+	for _, tt := range []struct {
+		name string
+		json string
+	}{
+		{"trino", `{"rawType":"row","arguments":[
+			{"kind":"NAMED_TYPE","value":{"fieldName":{"name":"a"},"typeSignature":{"rawType":"bigint","arguments":[]}}},
+			{"kind":"NAMED_TYPE","value":{"typeSignature":{"rawType":"array","arguments":[
+				{"kind":"TYPE","value":{"rawType":"varchar","arguments":[{"kind":"LONG","value":10}]}}]}}}]}`},
+		{"presto", `{"rawType":"row","arguments":[
+			{"kind":"NAMED_TYPE_SIGNATURE","value":{"fieldName":{"name":"a","delimited":false},"typeSignature":"bigint"}},
+			{"kind":"NAMED_TYPE_SIGNATURE","value":{"typeSignature":"array(varchar(10))"}}]}`},
+		{"text", `"row(a bigint, array(varchar(10)))"`},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			var sig Signature
+			require.NoError(t, json.Unmarshal([]byte(tt.json), &sig))
+			assert.Equal(t, want.String(), sig.String())
+			assert.Equal(t, KindNamedType, sig.Arguments[0].Kind)
+			assert.Equal(t, KindType, sig.Elem(1).Arguments[0].Kind)
+			assert.Equal(t, KindLong, sig.Elem(1).Elem(0).Arguments[0].Kind)
+		})
+	}
+
+	var sig Signature
+	assert.Error(t, json.Unmarshal([]byte(`{"rawType":"x","arguments":[{"kind":"UNKNOWN","value":1}]}`), &sig))
+}